@@ -0,0 +1,608 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+
+	"github.com/mailstepcz/serr"
+)
+
+var (
+	// ErrAggregationNotFound signifies that the response contains no aggregation of the requested name.
+	ErrAggregationNotFound = errors.New("aggregation not found")
+)
+
+// Aggregation is an aggregation of documents.
+type Aggregation interface {
+	Map() (Map, error)
+}
+
+// Aggs are named aggregations.
+type Aggs map[string]Aggregation
+
+// Map returns the query map corresponding to the aggregations.
+func (a Aggs) Map() (Map, error) {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	m := Map{Pairs: make([]KVPair, 0, len(a))}
+	for _, name := range names {
+		am, err := a[name].Map()
+		if err != nil {
+			return Map{}, serr.Wrap("mapping aggregation", err, serr.String("name", name))
+		}
+		m.Pairs = append(m.Pairs, KVPair{name, am})
+	}
+	return m, nil
+}
+
+// MarshalJSON marshals the aggregations into JSON.
+func (a Aggs) MarshalJSON() ([]byte, error) {
+	m, err := a.Map()
+	if err != nil {
+		return nil, err
+	}
+	return m.JSON(), nil
+}
+
+var _ json.Marshaler = Aggs{}
+
+func aggMap(kind string, body Map, sub Aggs) (Map, error) {
+	m := Map{Pairs: []KVPair{{kind, body}}}
+	if len(sub) > 0 {
+		sm, err := sub.Map()
+		if err != nil {
+			return Map{}, err
+		}
+		m.Pairs = append(m.Pairs, KVPair{"aggs", sm})
+	}
+	return m, nil
+}
+
+// AggOrder is an ordering of buckets.
+// The key is either _count, _key or the name of a single-value sub-aggregation.
+type AggOrder struct {
+	Key  string
+	Desc bool
+}
+
+func aggOrderMap(order []AggOrder) []Map {
+	l := make([]Map, 0, len(order))
+	for _, o := range order {
+		dir := "asc"
+		if o.Desc {
+			dir = "desc"
+		}
+		l = append(l, Map{Pairs: []KVPair{{o.Key, dir}}})
+	}
+	return l
+}
+
+// TermsAgg is a bucket aggregation over unique field values.
+type TermsAgg struct {
+	Field       string
+	Size        int
+	MinDocCount *int
+	// Missing is the value of documents without the field; it's marshalled by encoding/json.
+	Missing any
+	Order   []AggOrder
+	Aggs    Aggs
+}
+
+// Map returns the query map corresponding to the aggregation.
+func (a TermsAgg) Map() (Map, error) {
+	body := Map{Pairs: []KVPair{{"field", a.Field}}}
+	if a.Size > 0 {
+		body.Pairs = append(body.Pairs, KVPair{"size", a.Size})
+	}
+	if a.MinDocCount != nil {
+		body.Pairs = append(body.Pairs, KVPair{"min_doc_count", *a.MinDocCount})
+	}
+	if a.Missing != nil {
+		missing, err := json.Marshal(a.Missing)
+		if err != nil {
+			return Map{}, serr.Wrap("marshalling missing value", err, serr.String("field", a.Field))
+		}
+		body.Pairs = append(body.Pairs, KVPair{"missing", json.RawMessage(missing)})
+	}
+	if len(a.Order) > 0 {
+		body.Pairs = append(body.Pairs, KVPair{"order", aggOrderMap(a.Order)})
+	}
+	return aggMap("terms", body, a.Aggs)
+}
+
+// DateHistogramAgg is a bucket aggregation over date intervals.
+// Exactly one of CalendarInterval (e.g. month) and FixedInterval (e.g. 12h) shall be set.
+type DateHistogramAgg struct {
+	Field            string
+	CalendarInterval string
+	FixedInterval    string
+	Format           string
+	TimeZone         string
+	MinDocCount      *int
+	Order            []AggOrder
+	Aggs             Aggs
+}
+
+// Map returns the query map corresponding to the aggregation.
+func (a DateHistogramAgg) Map() (Map, error) {
+	body := Map{Pairs: []KVPair{{"field", a.Field}}}
+	switch {
+	case a.CalendarInterval != "" && a.FixedInterval == "":
+		body.Pairs = append(body.Pairs, KVPair{"calendar_interval", a.CalendarInterval})
+	case a.FixedInterval != "" && a.CalendarInterval == "":
+		body.Pairs = append(body.Pairs, KVPair{"fixed_interval", a.FixedInterval})
+	default:
+		return Map{}, serr.Wrap("exactly one of calendar and fixed interval shall be set", ErrOpensearchBadRequest, serr.String("field", a.Field))
+	}
+	if a.Format != "" {
+		body.Pairs = append(body.Pairs, KVPair{"format", a.Format})
+	}
+	if a.TimeZone != "" {
+		body.Pairs = append(body.Pairs, KVPair{"time_zone", a.TimeZone})
+	}
+	if a.MinDocCount != nil {
+		body.Pairs = append(body.Pairs, KVPair{"min_doc_count", *a.MinDocCount})
+	}
+	if len(a.Order) > 0 {
+		body.Pairs = append(body.Pairs, KVPair{"order", aggOrderMap(a.Order)})
+	}
+	return aggMap("date_histogram", body, a.Aggs)
+}
+
+// HistogramAgg is a bucket aggregation over numeric intervals.
+type HistogramAgg struct {
+	Field       string
+	Interval    float64
+	MinDocCount *int
+	Aggs        Aggs
+}
+
+// Map returns the query map corresponding to the aggregation.
+func (a HistogramAgg) Map() (Map, error) {
+	body := Map{Pairs: []KVPair{
+		{"field", a.Field},
+		{"interval", a.Interval},
+	}}
+	if a.MinDocCount != nil {
+		body.Pairs = append(body.Pairs, KVPair{"min_doc_count", *a.MinDocCount})
+	}
+	return aggMap("histogram", body, a.Aggs)
+}
+
+// AggRange is a range of a range aggregation. The From bound is inclusive, the To bound is exclusive.
+type AggRange struct {
+	Key  string
+	From *float64
+	To   *float64
+}
+
+// RangeAgg is a bucket aggregation over user-defined ranges.
+type RangeAgg struct {
+	Field  string
+	Ranges []AggRange
+	Aggs   Aggs
+}
+
+// Map returns the query map corresponding to the aggregation.
+func (a RangeAgg) Map() (Map, error) {
+	ranges := make([]Map, 0, len(a.Ranges))
+	for _, r := range a.Ranges {
+		rm := Map{Pairs: make([]KVPair, 0, 3)}
+		if r.Key != "" {
+			rm.Pairs = append(rm.Pairs, KVPair{"key", r.Key})
+		}
+		if r.From != nil {
+			rm.Pairs = append(rm.Pairs, KVPair{"from", *r.From})
+		}
+		if r.To != nil {
+			rm.Pairs = append(rm.Pairs, KVPair{"to", *r.To})
+		}
+		ranges = append(ranges, rm)
+	}
+	return aggMap("range", Map{Pairs: []KVPair{
+		{"field", a.Field},
+		{"ranges", ranges},
+	}}, a.Aggs)
+}
+
+// StatsAgg is a metric aggregation computing count, min, max, avg and sum.
+type StatsAgg struct {
+	Field string
+}
+
+// Map returns the query map corresponding to the aggregation.
+func (a StatsAgg) Map() (Map, error) {
+	return aggMap("stats", Map{Pairs: []KVPair{{"field", a.Field}}}, nil)
+}
+
+// CardinalityAgg is a metric aggregation approximating the count of distinct values.
+type CardinalityAgg struct {
+	Field              string
+	PrecisionThreshold int
+}
+
+// Map returns the query map corresponding to the aggregation.
+func (a CardinalityAgg) Map() (Map, error) {
+	body := Map{Pairs: []KVPair{{"field", a.Field}}}
+	if a.PrecisionThreshold > 0 {
+		body.Pairs = append(body.Pairs, KVPair{"precision_threshold", a.PrecisionThreshold})
+	}
+	return aggMap("cardinality", body, nil)
+}
+
+// PercentilesAgg is a metric aggregation computing percentiles.
+type PercentilesAgg struct {
+	Field    string
+	Percents []float64
+}
+
+// Map returns the query map corresponding to the aggregation.
+func (a PercentilesAgg) Map() (Map, error) {
+	body := Map{Pairs: []KVPair{{"field", a.Field}}}
+	if len(a.Percents) > 0 {
+		body.Pairs = append(body.Pairs, KVPair{"percents", a.Percents})
+	}
+	return aggMap("percentiles", body, nil)
+}
+
+// FilterAgg is a single bucket aggregation over the documents matching the expression.
+type FilterAgg struct {
+	Expr Expr
+	Aggs Aggs
+}
+
+// Map returns the query map corresponding to the aggregation.
+func (a FilterAgg) Map() (Map, error) {
+	em, err := osClause(a.Expr)
+	if err != nil {
+		return Map{}, err
+	}
+	return aggMap("filter", em, a.Aggs)
+}
+
+// FiltersAgg is a bucket aggregation with a bucket for each named expression.
+// Documents matching none of them fall into the bucket named OtherBucketKey if set.
+type FiltersAgg struct {
+	Filters        map[string]Expr
+	OtherBucketKey string
+	Aggs           Aggs
+}
+
+// Map returns the query map corresponding to the aggregation.
+func (a FiltersAgg) Map() (Map, error) {
+	names := make([]string, 0, len(a.Filters))
+	for name := range a.Filters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	filters := Map{Pairs: make([]KVPair, 0, len(a.Filters))}
+	for _, name := range names {
+		em, err := osClause(a.Filters[name])
+		if err != nil {
+			return Map{}, err
+		}
+		filters.Pairs = append(filters.Pairs, KVPair{name, em})
+	}
+	body := Map{Pairs: []KVPair{{"filters", filters}}}
+	if a.OtherBucketKey != "" {
+		body.Pairs = append(body.Pairs, KVPair{"other_bucket_key", a.OtherBucketKey})
+	}
+	return aggMap("filters", body, a.Aggs)
+}
+
+// NestedAgg is a single bucket aggregation over nested documents.
+type NestedAgg struct {
+	Path string
+	Aggs Aggs
+}
+
+// Map returns the query map corresponding to the aggregation.
+func (a NestedAgg) Map() (Map, error) {
+	return aggMap("nested", Map{Pairs: []KVPair{{"path", a.Path}}}, a.Aggs)
+}
+
+// TopHitsAgg is a metric aggregation returning the top documents of a bucket.
+// The OrderBy field has the same format as in [Search], Sort takes precedence over it.
+type TopHitsAgg struct {
+	Size    int
+	OrderBy string
+	Sort    Sort
+	Source  []string
+}
+
+// Map returns the query map corresponding to the aggregation.
+func (a TopHitsAgg) Map() (Map, error) {
+	body := Map{Pairs: make([]KVPair, 0, 3)}
+	if a.Size > 0 {
+		body.Pairs = append(body.Pairs, KVPair{"size", a.Size})
+	}
+	sort := a.Sort
+	if sort == nil {
+		sort = ParseSort(a.OrderBy)
+	}
+	if len(sort) > 0 {
+		sm, err := sort.Map()
+		if err != nil {
+			return Map{}, err
+		}
+		body.Pairs = append(body.Pairs, KVPair{"sort", sm})
+	}
+	if len(a.Source) > 0 {
+		body.Pairs = append(body.Pairs, KVPair{"_source", Map{Pairs: []KVPair{{"includes", a.Source}}}})
+	}
+	return aggMap("top_hits", body, nil)
+}
+
+var (
+	_ Aggregation = new(TermsAgg)
+	_ Aggregation = new(DateHistogramAgg)
+	_ Aggregation = new(HistogramAgg)
+	_ Aggregation = new(RangeAgg)
+	_ Aggregation = new(StatsAgg)
+	_ Aggregation = new(CardinalityAgg)
+	_ Aggregation = new(PercentilesAgg)
+	_ Aggregation = new(FilterAgg)
+	_ Aggregation = new(FiltersAgg)
+	_ Aggregation = new(NestedAgg)
+	_ Aggregation = new(TopHitsAgg)
+)
+
+// AggResults holds the results of named aggregations.
+type AggResults struct {
+	raw map[string]json.RawMessage
+}
+
+// UnmarshalJSON unmarshals the aggregation results from JSON.
+func (r *AggResults) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, &r.raw)
+}
+
+var _ json.Unmarshaler = new(AggResults)
+
+// Bucket is a bucket of a multi-bucket aggregation.
+// The key type depends on the aggregation; it's a string for terms of keyword fields and ranges,
+// a number for histograms and an epoch in milliseconds for date histograms.
+type Bucket[K any] struct {
+	Key         K
+	KeyAsString string
+	DocCount    int
+	From        *float64
+	To          *float64
+	Aggs        *AggResults
+}
+
+// UnmarshalJSON unmarshals the bucket from JSON.
+func (b *Bucket[K]) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for k, v := range raw {
+		var err error
+		switch k {
+		case "key":
+			err = json.Unmarshal(v, &b.Key)
+		case "key_as_string":
+			err = json.Unmarshal(v, &b.KeyAsString)
+		case "doc_count":
+			err = json.Unmarshal(v, &b.DocCount)
+		case "from":
+			err = json.Unmarshal(v, &b.From)
+		case "to":
+			err = json.Unmarshal(v, &b.To)
+		default:
+			addSubAgg(&b.Aggs, k, v)
+		}
+		if err != nil {
+			return serr.Wrap("unmarshalling bucket", err, serr.String("field", k))
+		}
+	}
+	return nil
+}
+
+// SingleBucket is the result of a single bucket aggregation such as [FilterAgg] or [NestedAgg].
+type SingleBucket struct {
+	DocCount int
+	Aggs     *AggResults
+}
+
+// UnmarshalJSON unmarshals the bucket from JSON.
+func (b *SingleBucket) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for k, v := range raw {
+		if k == "doc_count" {
+			if err := json.Unmarshal(v, &b.DocCount); err != nil {
+				return serr.Wrap("unmarshalling bucket", err, serr.String("field", k))
+			}
+			continue
+		}
+		addSubAgg(&b.Aggs, k, v)
+	}
+	return nil
+}
+
+var (
+	_ json.Unmarshaler = new(Bucket[string])
+	_ json.Unmarshaler = new(SingleBucket)
+)
+
+// addSubAgg adds an object-valued bucket attribute as a sub-aggregation result.
+func addSubAgg(r **AggResults, name string, v json.RawMessage) {
+	if len(v) == 0 || v[0] != '{' {
+		return
+	}
+	if *r == nil {
+		*r = &AggResults{raw: make(map[string]json.RawMessage)}
+	}
+	(*r).raw[name] = v
+}
+
+// StatsResult is the result of [StatsAgg]. The values are nil if no document had the field.
+type StatsResult struct {
+	Count int      `json:"count"`
+	Min   *float64 `json:"min"`
+	Max   *float64 `json:"max"`
+	Avg   *float64 `json:"avg"`
+	Sum   float64  `json:"sum"`
+}
+
+// AggBuckets returns the buckets of a terms, histogram, date histogram or range aggregation.
+func AggBuckets[K any](r *AggResults, name string) ([]Bucket[K], error) {
+	var res struct {
+		Buckets []Bucket[K] `json:"buckets"`
+	}
+	if err := r.decode(name, &res); err != nil {
+		return nil, err
+	}
+	return res.Buckets, nil
+}
+
+// AggTopHits returns the documents of a top hits aggregation and their total count.
+func AggTopHits[T any](r *AggResults, name string) ([]IDedDocument[T], int, error) {
+	var res struct {
+		Hits searchHits `json:"hits"`
+	}
+	if err := r.decode(name, &res); err != nil {
+		return nil, 0, err
+	}
+	docs, err := mapDocs[T](res.Hits.Hits)
+	if err != nil {
+		return nil, 0, err
+	}
+	return docs, res.Hits.Total.Value, nil
+}
+
+// Stats returns the result of a stats aggregation.
+func (r *AggResults) Stats(name string) (*StatsResult, error) {
+	var res StatsResult
+	if err := r.decode(name, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Cardinality returns the result of a cardinality aggregation.
+func (r *AggResults) Cardinality(name string) (int, error) {
+	var res struct {
+		Value int `json:"value"`
+	}
+	if err := r.decode(name, &res); err != nil {
+		return 0, err
+	}
+	return res.Value, nil
+}
+
+// Percentiles returns the result of a percentiles aggregation keyed by the percent (e.g. "99.0").
+// Percentiles without a value are omitted.
+func (r *AggResults) Percentiles(name string) (map[string]float64, error) {
+	var res struct {
+		Values map[string]*float64 `json:"values"`
+	}
+	if err := r.decode(name, &res); err != nil {
+		return nil, err
+	}
+	values := make(map[string]float64, len(res.Values))
+	for k, v := range res.Values {
+		if v != nil {
+			values[k] = *v
+		}
+	}
+	return values, nil
+}
+
+// SingleBucket returns the result of a filter or nested aggregation.
+func (r *AggResults) SingleBucket(name string) (*SingleBucket, error) {
+	var res SingleBucket
+	if err := r.decode(name, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Filters returns the buckets of a filters aggregation keyed by the filter name.
+func (r *AggResults) Filters(name string) (map[string]SingleBucket, error) {
+	var res struct {
+		Buckets map[string]SingleBucket `json:"buckets"`
+	}
+	if err := r.decode(name, &res); err != nil {
+		return nil, err
+	}
+	return res.Buckets, nil
+}
+
+func (r *AggResults) decode(name string, v any) error {
+	if r == nil {
+		return serr.Wrap("", ErrAggregationNotFound, serr.String("name", name))
+	}
+	b, ok := r.raw[name]
+	if !ok {
+		return serr.Wrap("", ErrAggregationNotFound, serr.String("name", name))
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return serr.Wrap("unmarshalling aggregation", err, serr.String("name", name))
+	}
+	return nil
+}
+
+// Aggregate runs the aggregations over the documents matching the expression. No documents are returned.
+func Aggregate(ctx context.Context, cl *opensearch.Client, index string, expr Expr, aggs Aggs, opts ...SearchOption) (*AggResults, error) {
+	query, err := buildQuery(expr, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(query)
+	}
+	size := 0
+	query.Size = &size
+	query.Aggs = aggs
+
+	b, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	req := opensearchapi.SearchReq{
+		Indices: []string{index},
+		Body:    bytes.NewReader(b),
+		Params:  searchParams(query),
+	}
+	var osResp searchResp
+	resp, err := cl.Do(ctx, req, &osResp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, osError(resp)
+	}
+
+	var res AggResults
+	if len(osResp.Aggregations) > 0 {
+		if err := json.Unmarshal(osResp.Aggregations, &res); err != nil {
+			return nil, err
+		}
+	}
+	return &res, nil
+}
+
+// WithAggregations adds the aggregations to the search.
+// The results are available in [SearchResponse].
+func WithAggregations(aggs Aggs) SearchOption {
+	return func(q *searchQuery) {
+		q.Aggs = aggs
+	}
+}
@@ -0,0 +1,162 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggsJSON(t *testing.T) {
+	req := require.New(t)
+
+	minDocCount := 1
+	aggs := Aggs{
+		"byStatus": TermsAgg{
+			Field: "status",
+			Size:  10,
+			Order: []AggOrder{{Key: "_count", Desc: true}},
+			Aggs: Aggs{
+				"perMonth": DateHistogramAgg{Field: "createdAt", CalendarInterval: "month", MinDocCount: &minDocCount},
+				"weight":   StatsAgg{Field: "weight"},
+			},
+		},
+		"express": FilterAgg{
+			Expr: Eq[bool]{Ident: "express", Value: true},
+			Aggs: Aggs{"customers": CardinalityAgg{Field: "customerId"}},
+		},
+		"latency": PercentilesAgg{Field: "latency", Percents: []float64{50, 99}},
+	}
+
+	b, err := json.Marshal(aggs)
+	req.NoError(err)
+	req.JSONEq(`{
+		"byStatus":{
+			"terms":{"field":"status","size":10,"order":[{"_count":"desc"}]},
+			"aggs":{
+				"perMonth":{"date_histogram":{"field":"createdAt","calendar_interval":"month","min_doc_count":1}},
+				"weight":{"stats":{"field":"weight"}}
+			}
+		},
+		"express":{
+			"filter":{"term":{"express":true}},
+			"aggs":{"customers":{"cardinality":{"field":"customerId"}}}
+		},
+		"latency":{"percentiles":{"field":"latency","percents":[50,99]}}
+	}`, string(b))
+}
+
+func TestTermsAggMissing(t *testing.T) {
+	req := require.New(t)
+
+	aggs := Aggs{
+		"byWeight":    TermsAgg{Field: "weight", Missing: int64(0)},
+		"byCreatedAt": TermsAgg{Field: "createdAt", Missing: time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+	}
+	b, err := json.Marshal(aggs)
+	req.NoError(err)
+	req.JSONEq(`{
+		"byWeight":{"terms":{"field":"weight","missing":0}},
+		"byCreatedAt":{"terms":{"field":"createdAt","missing":"2026-10-17T00:00:00Z"}}
+	}`, string(b))
+
+	_, err = TermsAgg{Field: "weight", Missing: func() {}}.Map()
+	req.Error(err)
+}
+
+func TestAggregateOptions(t *testing.T) {
+	req := require.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req.Equal("/events-2026.10.17,events-2026.10.18/_search", r.URL.Path)
+		req.Equal("true", r.URL.Query().Get("ignore_unavailable"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"hits":{"total":{"value":1,"relation":"eq"},"hits":[]},"aggregations":{"customers":{"value":1}}}`)
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	res, err := Aggregate(context.Background(), cl, "events-2026.10.17,events-2026.10.18", And{}, Aggs{"customers": CardinalityAgg{Field: "customerId"}}, WithIgnoreUnavailable())
+	req.NoError(err)
+	n, err := res.Cardinality("customers")
+	req.NoError(err)
+	req.Equal(1, n)
+}
+
+func TestDateHistogramAggInterval(t *testing.T) {
+	req := require.New(t)
+
+	_, err := DateHistogramAgg{Field: "createdAt"}.Map()
+	req.ErrorIs(err, ErrOpensearchBadRequest)
+
+	_, err = DateHistogramAgg{Field: "createdAt", CalendarInterval: "month", FixedInterval: "1d"}.Map()
+	req.ErrorIs(err, ErrOpensearchBadRequest)
+}
+
+func TestAggResults(t *testing.T) {
+	req := require.New(t)
+
+	var res AggResults
+	err := json.Unmarshal([]byte(`{
+		"byStatus":{"doc_count_error_upper_bound":0,"sum_other_doc_count":0,"buckets":[
+			{"key":"shipped","doc_count":12,"weight":{"count":12,"min":1,"max":5,"avg":2.5,"sum":30}},
+			{"key":"delivered","doc_count":3,"weight":{"count":3,"min":null,"max":null,"avg":null,"sum":0}}
+		]},
+		"perMonth":{"buckets":[{"key_as_string":"2026-10-01","key":1790812800000,"doc_count":4}]},
+		"express":{"doc_count":7,"customers":{"value":5}},
+		"latency":{"values":{"50.0":12.5,"99.0":null}},
+		"top":{"hits":{"total":{"value":2,"relation":"eq"},"hits":[{"_id":"1","_source":{"name":"a"}}]}}
+	}`), &res)
+	req.NoError(err)
+
+	buckets, err := AggBuckets[string](&res, "byStatus")
+	req.NoError(err)
+	req.Len(buckets, 2)
+	req.Equal("shipped", buckets[0].Key)
+	req.Equal(12, buckets[0].DocCount)
+	stats, err := buckets[0].Aggs.Stats("weight")
+	req.NoError(err)
+	req.Equal(12, stats.Count)
+	req.InDelta(2.5, *stats.Avg, 0)
+	stats, err = buckets[1].Aggs.Stats("weight")
+	req.NoError(err)
+	req.Nil(stats.Avg)
+
+	months, err := AggBuckets[int64](&res, "perMonth")
+	req.NoError(err)
+	req.Equal(int64(1790812800000), months[0].Key)
+	req.Equal("2026-10-01", months[0].KeyAsString)
+	req.Nil(months[0].Aggs)
+
+	express, err := res.SingleBucket("express")
+	req.NoError(err)
+	req.Equal(7, express.DocCount)
+	customers, err := express.Aggs.Cardinality("customers")
+	req.NoError(err)
+	req.Equal(5, customers)
+
+	latency, err := res.Percentiles("latency")
+	req.NoError(err)
+	req.Equal(map[string]float64{"50.0": 12.5}, latency)
+
+	type doc struct {
+		Name string `json:"name"`
+	}
+	docs, total, err := AggTopHits[doc](&res, "top")
+	req.NoError(err)
+	req.Equal(2, total)
+	req.Equal("a", docs[0].Document.Name)
+
+	_, err = res.Stats("missing")
+	req.ErrorIs(err, ErrAggregationNotFound)
+	_, err = months[0].Aggs.Stats("missing")
+	req.ErrorIs(err, ErrAggregationNotFound)
+}
@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	"time"
 
 	"github.com/opensearch-project/opensearch-go/v4"
 	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
@@ -17,11 +19,67 @@ import (
 var (
 	// ErrBulkItemError fails if there was an error in the bulk response.
 	ErrBulkItemError = errors.New("bulk item error")
+	// ErrBulkItemRetriable signifies that a bulk item was rejected temporarily (429 or 503) and can be resubmitted.
+	ErrBulkItemRetriable = errors.New("bulk item rejected, retriable")
 )
 
+// Retry policy defaults.
+const (
+	DefaultRetryMaxAttempts    = 5
+	DefaultRetryInitialBackoff = 100 * time.Millisecond
+	DefaultRetryMaxBackoff     = 10 * time.Second
+)
+
+// RetryPolicy is an exponential backoff policy with full jitter. Zero values are replaced with defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func (p RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return DefaultRetryMaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns a random delay between zero and the exponentially growing ceiling.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	initial, ceiling := p.InitialBackoff, p.MaxBackoff
+	if initial <= 0 {
+		initial = DefaultRetryInitialBackoff
+	}
+	if ceiling <= 0 {
+		ceiling = DefaultRetryMaxBackoff
+	}
+	d := ceiling
+	if attempt < 32 && initial<<attempt < ceiling {
+		d = initial << attempt
+	}
+	return time.Duration(rand.Int64N(int64(d) + 1)) // nolint:gosec // jitter doesn't need a secure source
+}
+
+// BulkOption allows customization of Bulk behavior.
+type BulkOption func(*bulkConfig)
+
+type bulkConfig struct {
+	retry *RetryPolicy
+}
+
+// WithBulkRetry resubmits items rejected with [ErrBulkItemRetriable] according to the policy,
+// and so are whole requests rejected with 429 or 503.
+// Items still rejected after the last attempt are reported with [ErrBulkItemRetriable].
+func WithBulkRetry(policy RetryPolicy) BulkOption {
+	return func(c *bulkConfig) {
+		c.retry = &policy
+	}
+}
+
 // BulkItemResult holds the classified outcome of one non-success bulk item.
 // Error wraps one of the package sentinel errors (ErrDocumentHasNewerVersion,
-// ErrDocumentNotFound, ErrBulkItemError) so callers can branch with errors.Is.
+// ErrDocumentNotFound, ErrBulkItemRetriable, ErrBulkItemError) so callers can branch with errors.Is.
 type BulkItemResult struct {
 	ID    string
 	Error error
@@ -55,34 +113,158 @@ const (
 )
 
 // BulkOperation for OpenSearch requests.
+// For OpUpdate, Doc is the partial document to merge into the existing one. DocAsUpsert indexes Doc
+// if the document doesn't exist, Upsert is an explicit document indexed in that case. If Script is set,
+// the document is updated by the script instead of Doc.
+// If IfSeqNo and IfPrimaryTerm are set, the operation fails with [ErrDocumentHasNewerVersion] unless the document
+// has the given sequence number and primary term.
 type BulkOperation[T any] struct {
-	OperationType BulkOperationType `json:"-"`
-	ID            string            `json:"id"`
-	Index         string            `json:"index"`
-	Doc           *T                `json:"doc"`
+	OperationType   BulkOperationType `json:"-"`
+	ID              string            `json:"id"`
+	Index           string            `json:"index"`
+	Doc             *T                `json:"doc"`
+	DocAsUpsert     bool              `json:"docAsUpsert"`
+	Upsert          *T                `json:"upsert"`
+	Script          *Script           `json:"script"`
+	ScriptedUpsert  bool              `json:"scriptedUpsert"`
+	RetryOnConflict int               `json:"retryOnConflict"`
+	IfSeqNo         *int              `json:"ifSeqNo"`
+	IfPrimaryTerm   *int              `json:"ifPrimaryTerm"`
+}
+
+// Script is a script with parameters, e.g. for scripted updates.
+type Script struct {
+	Source string         `json:"source"`
+	Lang   string         `json:"lang,omitempty"`
+	Params map[string]any `json:"params,omitempty"`
+}
+
+type bulkUpdateBody[T any] struct {
+	Doc            *T      `json:"doc,omitempty"`
+	DocAsUpsert    bool    `json:"doc_as_upsert,omitempty"`
+	Script         *Script `json:"script,omitempty"`
+	ScriptedUpsert bool    `json:"scripted_upsert,omitempty"`
+	Upsert         *T      `json:"upsert,omitempty"`
 }
 
 // Bulk sends a bulk request with the specified ops.
 // Returns a *BulkResult with per-item outcomes for all non-success items; error is non-nil only on request-level failure.
-func Bulk[T any](ctx context.Context, cl *opensearch.Client, docs []BulkOperation[T]) (*BulkResult, error) {
-	return bulk(ctx, cl, docs, nil)
+func Bulk[T any](ctx context.Context, cl *opensearch.Client, docs []BulkOperation[T], opts ...BulkOption) (*BulkResult, error) {
+	return bulk(ctx, cl, docs, nil, opts...)
 }
 
 // BulkWithRefresh sends a bulk request with the specified ops and refresh = true parameter.
 // Returns a *BulkResult with per-item outcomes for all non-success items; error is non-nil only on request-level failure.
 // https://opensearch.org/docs/latest/api-reference/document-apis/bulk/#query-parameters
-func BulkWithRefresh[T any](ctx context.Context, cl *opensearch.Client, docs []BulkOperation[T]) (*BulkResult, error) {
-	return bulk(ctx, cl, docs, &opensearchapi.BulkParams{Refresh: "true"})
+func BulkWithRefresh[T any](ctx context.Context, cl *opensearch.Client, docs []BulkOperation[T], opts ...BulkOption) (*BulkResult, error) {
+	return bulk(ctx, cl, docs, &opensearchapi.BulkParams{Refresh: "true"}, opts...)
 }
 
-func bulk[T any](ctx context.Context, cl *opensearch.Client, ops []BulkOperation[T], params *opensearchapi.BulkParams) (*BulkResult, error) {
-	var buf bytes.Buffer
-	if err := buildBulkBody(ops, &buf); err != nil {
+func bulk[T any](ctx context.Context, cl *opensearch.Client, ops []BulkOperation[T], params *opensearchapi.BulkParams, opts ...BulkOption) (*BulkResult, error) {
+	var cfg bulkConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	outcomes, err := bulkOps(ctx, cl, ops, params, cfg.retry)
+	if err != nil {
 		return nil, err
 	}
 
+	var result BulkResult
+	for _, it := range outcomes {
+		if it.Error != nil {
+			result.Items = append(result.Items, it)
+		}
+	}
+
+	return &result, nil
+}
+
+// bulkOps sends the ops and returns an outcome for each of them, in the same order.
+// Items rejected as retriable, as well as whole requests rejected with 429 or 503, are resubmitted according to the retry policy.
+// The error is non-nil only if the request fails as a whole before any outcome is known.
+func bulkOps[T any](ctx context.Context, cl *opensearch.Client, ops []BulkOperation[T], params *opensearchapi.BulkParams, retry *RetryPolicy) ([]BulkItemResult, error) {
+	// nolint:makezero
+	outcomes := make([]BulkItemResult, len(ops))
+	// nolint:makezero
+	pending := make([]int, len(ops))
+	for i := range ops {
+		outcomes[i].ID = ops[i].ID
+		pending[i] = i
+	}
+
+	answered := false
+	for attempt := 0; ; attempt++ {
+		batch := make([]BulkOperation[T], 0, len(pending))
+		for _, i := range pending {
+			batch = append(batch, ops[i])
+		}
+
+		var buf bytes.Buffer
+		if err := buildBulkBody(batch, &buf); err != nil {
+			return nil, err
+		}
+
+		items, err := sendBulk(ctx, cl, &buf, params)
+		if err != nil {
+			// whole requests rejected as overloaded are resubmitted like the items
+			if errors.Is(err, ErrBulkItemRetriable) && retry != nil && attempt+1 < retry.maxAttempts() && waitBackoff(ctx, retry.backoff(attempt)) {
+				continue
+			}
+			if !answered {
+				return nil, err
+			}
+			// the items keep their retriable errors, the request error is added to them
+			for _, i := range pending {
+				outcomes[i].Error = errors.Join(outcomes[i].Error, err)
+			}
+			return outcomes, nil
+		}
+		answered = true
+
+		var retriable []int
+		for j, i := range pending {
+			if j >= len(items) {
+				outcomes[i].Error = serr.Wrap("bulk item missing in response", ErrBulkItemError, serr.String("id", ops[i].ID))
+				continue
+			}
+			if items[j].ID != "" {
+				outcomes[i].ID = items[j].ID
+			}
+			outcomes[i].Error = bulkItemError(items[j])
+			if errors.Is(outcomes[i].Error, ErrBulkItemRetriable) {
+				retriable = append(retriable, i)
+			}
+		}
+
+		if len(retriable) == 0 || retry == nil || attempt+1 >= retry.maxAttempts() {
+			return outcomes, nil
+		}
+
+		if !waitBackoff(ctx, retry.backoff(attempt)) {
+			return outcomes, nil
+		}
+		pending = retriable
+	}
+}
+
+// waitBackoff waits for the backoff delay. It returns false if the context is done first.
+func waitBackoff(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sendBulk sends the bulk request body and returns the response items in the order of the operations.
+func sendBulk(ctx context.Context, cl *opensearch.Client, body io.Reader, params *opensearchapi.BulkParams) ([]opensearchapi.BulkRespItem, error) {
 	req := opensearchapi.BulkReq{
-		Body: &buf,
+		Body: body,
 	}
 
 	if params != nil {
@@ -97,37 +279,46 @@ func bulk[T any](ctx context.Context, cl *opensearch.Client, ops []BulkOperation
 	defer resp.Body.Close() // nolint:errcheck
 
 	if resp.IsError() {
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			return nil, errors.Join(ErrBulkItemRetriable, osError(resp))
+		}
 		return nil, osError(resp)
 	}
 
-	var result BulkResult
-	if bulkResponse.Errors && bulkResponse.Items != nil {
-		for _, items := range bulkResponse.Items {
-			for _, item := range items {
-				if item.Error == nil {
-					continue
-				}
+	items := make([]opensearchapi.BulkRespItem, 0, len(bulkResponse.Items))
+	for _, m := range bulkResponse.Items {
+		for _, item := range m {
+			items = append(items, item)
+		}
+	}
 
-				sentinel := ErrBulkItemError
-				switch item.Status {
-				case http.StatusConflict:
-					sentinel = ErrDocumentHasNewerVersion
-				case http.StatusNotFound:
-					sentinel = ErrDocumentNotFound
-				}
+	return items, nil
+}
 
-				result.Items = append(result.Items, BulkItemResult{
-					ID: item.ID,
-					Error: serr.Wrap("bulk item failed", sentinel,
-						serr.String("index", item.Index),
-						serr.String("reason", item.Error.Reason),
-					),
-				})
-			}
+// bulkItemError classifies the outcome of a bulk item. It returns nil if the item succeeded.
+func bulkItemError(item opensearchapi.BulkRespItem) error {
+	if item.Error == nil {
+		return nil
+	}
+
+	sentinel := ErrBulkItemError
+	switch item.Status {
+	case http.StatusConflict:
+		sentinel = ErrDocumentHasNewerVersion
+	case http.StatusNotFound:
+		sentinel = ErrDocumentNotFound
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		sentinel = ErrBulkItemRetriable
+	default:
+		if item.Error.Type == "es_rejected_execution_exception" {
+			sentinel = ErrBulkItemRetriable
 		}
 	}
 
-	return &result, nil
+	return serr.Wrap("bulk item failed", sentinel,
+		serr.String("index", item.Index),
+		serr.String("reason", item.Error.Reason),
+	)
 }
 
 func buildBulkBody[T any](ops []BulkOperation[T], w io.Writer) error {
@@ -137,21 +328,50 @@ func buildBulkBody[T any](ops []BulkOperation[T], w io.Writer) error {
 			"_index": op.Index,
 			"_id":    op.ID,
 		}
-		if op.OperationType != OpDelete && op.Doc != nil {
+		// the update API doesn't support external versioning
+		if (op.OperationType == OpIndex || op.OperationType == OpCreate) && op.Doc != nil {
 			if vd, ok := any(op.Doc).(VersionedDocument); ok {
 				meta["version"] = vd.Version()
 				meta["version_type"] = string(vd.VersionType())
 			}
 		}
+		if op.IfSeqNo != nil && op.IfPrimaryTerm != nil {
+			meta["if_seq_no"] = *op.IfSeqNo
+			meta["if_primary_term"] = *op.IfPrimaryTerm
+		}
+		if op.OperationType == OpUpdate && op.RetryOnConflict > 0 {
+			meta["retry_on_conflict"] = op.RetryOnConflict
+		}
 		if err := encoder.Encode(map[string]any{
 			string(op.OperationType): meta,
 		}); err != nil {
 			return serr.Wrap("marshalling meta JSON", err, serr.String("index", op.Index), serr.String("id", op.ID))
 		}
 
-		if op.OperationType != OpDelete && op.Doc != nil {
-			if err := encoder.Encode(op.Doc); err != nil {
-				return serr.Wrap("marshalling document JSON", err, serr.String("operationType", string(op.OperationType)))
+		switch op.OperationType {
+		case OpDelete:
+		case OpUpdate:
+			body := bulkUpdateBody[T]{
+				Upsert: op.Upsert,
+			}
+			switch {
+			case op.Script != nil:
+				body.Script = op.Script
+				body.ScriptedUpsert = op.ScriptedUpsert
+			case op.Doc != nil:
+				body.Doc = op.Doc
+				body.DocAsUpsert = op.DocAsUpsert
+			default:
+				return serr.Wrap("update requires a document or a script", ErrOpensearchBadRequest, serr.String("index", op.Index), serr.String("id", op.ID))
+			}
+			if err := encoder.Encode(body); err != nil {
+				return serr.Wrap("marshalling update JSON", err, serr.String("operationType", string(op.OperationType)))
+			}
+		default:
+			if op.Doc != nil {
+				if err := encoder.Encode(op.Doc); err != nil {
+					return serr.Wrap("marshalling document JSON", err, serr.String("operationType", string(op.OperationType)))
+				}
 			}
 		}
 	}
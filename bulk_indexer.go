@@ -0,0 +1,340 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+
+	"github.com/mailstepcz/serr"
+)
+
+// Bulk indexer defaults.
+const (
+	DefaultBulkFlushDocs     = 1000
+	DefaultBulkFlushBytes    = 5 << 20
+	DefaultBulkFlushInterval = time.Second
+)
+
+var (
+	// ErrBulkIndexerClosed signifies that an operation was added to a closed bulk indexer.
+	ErrBulkIndexerClosed = errors.New("bulk indexer is closed")
+)
+
+// BulkIndexerConfig configures a [BulkIndexer]. Zero values are replaced with defaults.
+type BulkIndexerConfig[T any] struct {
+	// FlushDocs is the number of operations after which a batch is sent.
+	FlushDocs int
+	// FlushBytes is the size of the request body after which a batch is sent.
+	FlushBytes int
+	// FlushInterval is the period after which a non-empty batch is sent.
+	FlushInterval time.Duration
+	// Workers is the number of concurrent bulk requests, runtime.NumCPU() by default.
+	Workers int
+	// QueueSize is the number of batches waiting for a worker before [BulkIndexer.Add] blocks, Workers by default.
+	QueueSize int
+	// Refresh is the refresh parameter of the bulk requests.
+	Refresh RefreshType
+	// Retry is the policy for resubmitting retriable items, they aren't resubmitted if nil.
+	Retry *RetryPolicy
+
+	// OnSuccess is called for every succeeded operation.
+	OnSuccess func(ctx context.Context, op BulkOperation[T])
+	// OnFailure is called for every failed operation. The error of the result wraps one of the package
+	// sentinel errors as in [BulkResult]; if the whole batch failed, it wraps [ErrBulkItemError]
+	// or [ErrBulkItemRetriable] along with the request-level error.
+	OnFailure func(ctx context.Context, op BulkOperation[T], res BulkItemResult)
+}
+
+// BulkIndexer sends bulk operations in batches.
+// A batch is sent when it reaches the configured number of operations or size, or when the flush interval elapses.
+// Batches are sent by a pool of workers; when all of them are busy and the queue is full, [BulkIndexer.Add] blocks.
+type BulkIndexer[T any] struct {
+	cl     *opensearch.Client
+	ctx    context.Context
+	cfg    BulkIndexerConfig[T]
+	params *opensearchapi.BulkParams
+
+	mu     sync.Mutex
+	batch  *bulkBatch[T]
+	closed bool
+
+	queue   chan *bulkBatch[T]
+	stop    chan struct{}
+	flusher sync.WaitGroup
+	senders sync.WaitGroup
+	workers sync.WaitGroup
+}
+
+type bulkBatch[T any] struct {
+	ops  []BulkOperation[T]
+	size int
+}
+
+// NewBulkIndexer creates a new bulk indexer and starts its workers.
+// The context is used for the bulk requests sent by the workers and passed to the callbacks.
+// [BulkIndexer.Close] shall be called to send the remaining operations and stop the workers.
+func NewBulkIndexer[T any](ctx context.Context, cl *opensearch.Client, cfg BulkIndexerConfig[T]) *BulkIndexer[T] {
+	if cfg.FlushDocs <= 0 {
+		cfg.FlushDocs = DefaultBulkFlushDocs
+	}
+	if cfg.FlushBytes <= 0 {
+		cfg.FlushBytes = DefaultBulkFlushBytes
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultBulkFlushInterval
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = cfg.Workers
+	}
+
+	bi := &BulkIndexer[T]{
+		cl:    cl,
+		ctx:   ctx,
+		cfg:   cfg,
+		batch: new(bulkBatch[T]),
+		queue: make(chan *bulkBatch[T], cfg.QueueSize),
+		stop:  make(chan struct{}),
+	}
+	if cfg.Refresh != "" {
+		bi.params = &opensearchapi.BulkParams{Refresh: string(cfg.Refresh)}
+	}
+
+	bi.workers.Add(cfg.Workers)
+	for range cfg.Workers {
+		go bi.work()
+	}
+	bi.flusher.Add(1)
+	go bi.flushPeriodically()
+
+	return bi
+}
+
+// Add adds the operation to the current batch.
+// It blocks if a batch has to be sent and the queue is full. If the context is done meanwhile,
+// the context error is returned and the operation isn't added, so it can be added again;
+// the batches are kept to be sent later.
+func (bi *BulkIndexer[T]) Add(ctx context.Context, op BulkOperation[T]) error {
+	var buf bytes.Buffer
+	if err := buildBulkBody([]BulkOperation[T]{op}, &buf); err != nil {
+		return err
+	}
+
+	for {
+		bi.mu.Lock()
+		if bi.closed {
+			bi.mu.Unlock()
+			return ErrBulkIndexerClosed
+		}
+		// make room for the operation if it would overflow the batch
+		if len(bi.batch.ops) == 0 || bi.batch.size+buf.Len() <= bi.cfg.FlushBytes {
+			break
+		}
+		full := bi.detach()
+		bi.senders.Add(1)
+		bi.mu.Unlock()
+
+		ok := bi.enqueue(full, ctx.Done())
+		bi.senders.Done()
+		if !ok {
+			bi.putBack(full)
+			return ctx.Err()
+		}
+	}
+
+	bi.batch.ops = append(bi.batch.ops, op)
+	bi.batch.size += buf.Len()
+
+	if len(bi.batch.ops) < bi.cfg.FlushDocs && bi.batch.size < bi.cfg.FlushBytes {
+		bi.mu.Unlock()
+		return nil
+	}
+	full := bi.detach()
+	bi.senders.Add(1)
+	bi.mu.Unlock()
+	defer bi.senders.Done()
+
+	if !bi.enqueue(full, ctx.Done()) {
+		// the operation is the last one of the batch
+		full.ops = full.ops[:len(full.ops)-1]
+		full.size -= buf.Len()
+		bi.putBack(full)
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Flush sends the current batch without waiting for the thresholds.
+// If the context is done before the batch is queued, the context error is returned and the batch is kept to be sent later.
+func (bi *BulkIndexer[T]) Flush(ctx context.Context) error {
+	bi.mu.Lock()
+	if bi.closed {
+		bi.mu.Unlock()
+		return ErrBulkIndexerClosed
+	}
+	b := bi.detach()
+	if b == nil {
+		bi.mu.Unlock()
+		return nil
+	}
+	bi.senders.Add(1)
+	bi.mu.Unlock()
+	defer bi.senders.Done()
+
+	if !bi.enqueue(b, ctx.Done()) {
+		bi.putBack(b)
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Close sends the remaining operations and waits until all batches are processed.
+// If the context is done before the last batch is queued, its operations are reported as failed
+// with [ErrBulkItemError] and the context error. Close is safe to call multiple times.
+func (bi *BulkIndexer[T]) Close(ctx context.Context) error {
+	bi.mu.Lock()
+	if bi.closed {
+		bi.mu.Unlock()
+		return nil
+	}
+	bi.closed = true
+	bi.mu.Unlock()
+
+	// the flusher and the blocked senders put their batches back and return
+	close(bi.stop)
+	bi.flusher.Wait()
+	bi.senders.Wait()
+
+	// nobody else touches the batch or the queue from now on
+	var err error
+	if b := bi.detach(); b != nil {
+		select {
+		case bi.queue <- b:
+		case <-ctx.Done():
+			err = ctx.Err()
+			bi.fail(b, err)
+		}
+	}
+	close(bi.queue)
+
+	done := make(chan struct{})
+	go func() {
+		bi.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// detach takes the current batch, it returns nil if the batch is empty. The caller shall hold the lock.
+func (bi *BulkIndexer[T]) detach() *bulkBatch[T] {
+	if len(bi.batch.ops) == 0 {
+		return nil
+	}
+	b := bi.batch
+	bi.batch = new(bulkBatch[T])
+	return b
+}
+
+// enqueue passes the batch to the workers. The caller shall not hold the lock.
+// If the indexer is being closed, the batch is put back to be sent by [BulkIndexer.Close].
+// If done is closed first, false is returned and the caller shall put the batch back.
+func (bi *BulkIndexer[T]) enqueue(b *bulkBatch[T], done <-chan struct{}) bool {
+	select {
+	case bi.queue <- b:
+		return true
+	case <-bi.stop:
+		bi.putBack(b)
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// putBack puts the batch in front of the current batch. The caller shall not hold the lock.
+func (bi *BulkIndexer[T]) putBack(b *bulkBatch[T]) {
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+	b.ops = append(b.ops, bi.batch.ops...)
+	b.size += bi.batch.size
+	bi.batch = b
+}
+
+func (bi *BulkIndexer[T]) flushPeriodically() {
+	defer bi.flusher.Done()
+
+	ticker := time.NewTicker(bi.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			bi.mu.Lock()
+			var b *bulkBatch[T]
+			if !bi.closed {
+				b = bi.detach()
+			}
+			bi.mu.Unlock()
+			if b != nil {
+				bi.enqueue(b, nil)
+			}
+		case <-bi.stop:
+			return
+		}
+	}
+}
+
+func (bi *BulkIndexer[T]) work() {
+	defer bi.workers.Done()
+
+	for b := range bi.queue {
+		bi.send(b)
+	}
+}
+
+// fail reports the operations of the batch which couldn't be sent as failed.
+func (bi *BulkIndexer[T]) fail(b *bulkBatch[T], err error) {
+	if bi.cfg.OnFailure == nil {
+		return
+	}
+	err = serr.Wrap("bulk batch not sent", errors.Join(ErrBulkItemError, err))
+	for _, op := range b.ops {
+		bi.cfg.OnFailure(bi.ctx, op, BulkItemResult{ID: op.ID, Error: err})
+	}
+}
+
+func (bi *BulkIndexer[T]) send(b *bulkBatch[T]) {
+	outcomes, err := bulkOps(bi.ctx, bi.cl, b.ops, bi.params, bi.cfg.Retry)
+	if err != nil && !errors.Is(err, ErrBulkItemRetriable) {
+		err = serr.Wrap("bulk request failed", errors.Join(ErrBulkItemError, err))
+	}
+	for i, op := range b.ops {
+		res := BulkItemResult{ID: op.ID, Error: err}
+		if err == nil {
+			res = outcomes[i]
+		}
+
+		if res.Error != nil {
+			if bi.cfg.OnFailure != nil {
+				bi.cfg.OnFailure(bi.ctx, op, res)
+			}
+			continue
+		}
+		if bi.cfg.OnSuccess != nil {
+			bi.cfg.OnSuccess(bi.ctx, op)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// newBulkServer responds to bulk requests, failing items whose ID starts with "conflict".
+func newBulkServer(t *testing.T, batchSizes *[]int, mu *sync.Mutex) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			return
+		}
+		var items []string
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			var action struct {
+				Index *struct {
+					ID string `json:"_id"`
+				} `json:"index"`
+			}
+			if err := json.Unmarshal(sc.Bytes(), &action); err != nil {
+				t.Error(err)
+				return
+			}
+			if action.Index == nil {
+				continue // document line
+			}
+			id := action.Index.ID
+			if strings.HasPrefix(id, "conflict") {
+				items = append(items, fmt.Sprintf(`{"index":{"_id":%q,"status":409,"error":{"type":"version_conflict_engine_exception","reason":"conflict"}}}`, id))
+			} else {
+				items = append(items, fmt.Sprintf(`{"index":{"_id":%q,"status":201}}`, id))
+			}
+		}
+		mu.Lock()
+		*batchSizes = append(*batchSizes, len(items))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"errors":true,"items":[%s]}`, strings.Join(items, ","))
+	}))
+}
+
+func TestBulkIndexer(t *testing.T) {
+	req := require.New(t)
+
+	var (
+		mu         sync.Mutex
+		batchSizes []int
+		succeeded  []string
+		failed     []BulkItemResult
+	)
+	srv := newBulkServer(t, &batchSizes, &mu)
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	type doc struct {
+		Name string `json:"name"`
+	}
+	ctx := context.Background()
+	bi := NewBulkIndexer(ctx, cl, BulkIndexerConfig[doc]{
+		FlushDocs:     3,
+		FlushInterval: time.Hour,
+		Workers:       2,
+		OnSuccess: func(_ context.Context, op BulkOperation[doc]) {
+			mu.Lock()
+			defer mu.Unlock()
+			succeeded = append(succeeded, op.ID)
+		},
+		OnFailure: func(_ context.Context, _ BulkOperation[doc], res BulkItemResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, res)
+		},
+	})
+
+	for i := range 7 {
+		id := fmt.Sprintf("ID-%d", i)
+		if i == 4 {
+			id = "conflict-4"
+		}
+		req.NoError(bi.Add(ctx, BulkOperation[doc]{OperationType: OpIndex, ID: id, Index: "test-index", Doc: &doc{Name: id}}))
+	}
+	req.NoError(bi.Close(ctx))
+	req.NoError(bi.Close(ctx))
+	req.ErrorIs(bi.Add(ctx, BulkOperation[doc]{OperationType: OpIndex, ID: "late", Index: "test-index"}), ErrBulkIndexerClosed)
+
+	req.ElementsMatch([]int{3, 3, 1}, batchSizes)
+	req.ElementsMatch([]string{"ID-0", "ID-1", "ID-2", "ID-3", "ID-5", "ID-6"}, succeeded)
+	req.Len(failed, 1)
+	req.Equal("conflict-4", failed[0].ID)
+	req.ErrorIs(failed[0].Error, ErrDocumentHasNewerVersion)
+}
+
+func TestBulkIndexerFlushBytes(t *testing.T) {
+	req := require.New(t)
+
+	var (
+		mu         sync.Mutex
+		batchSizes []int
+	)
+	srv := newBulkServer(t, &batchSizes, &mu)
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	type doc struct {
+		Name string `json:"name"`
+	}
+	ctx := context.Background()
+	// each operation takes 64 bytes, so two of them fit into a batch
+	bi := NewBulkIndexer(ctx, cl, BulkIndexerConfig[doc]{
+		FlushBytes:    140,
+		FlushInterval: time.Hour,
+		Workers:       1,
+	})
+	for i := range 5 {
+		req.NoError(bi.Add(ctx, BulkOperation[doc]{OperationType: OpIndex, ID: fmt.Sprintf("ID-%d", i), Index: "test-index", Doc: &doc{Name: "1234567"}}))
+	}
+	req.NoError(bi.Close(ctx))
+
+	req.Equal([]int{2, 2, 1}, batchSizes)
+}
+
+// newBlockingBulkServer is like newBulkServer but signals each request and responds once release is closed.
+func newBlockingBulkServer(t *testing.T, batchSizes *[]int, mu *sync.Mutex, started chan<- struct{}, release <-chan struct{}) *httptest.Server {
+	inner := newBulkServer(t, batchSizes, mu)
+	t.Cleanup(inner.Close)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		inner.Config.Handler.ServeHTTP(w, r)
+	}))
+}
+
+func TestBulkIndexerCloseCancelled(t *testing.T) {
+	req := require.New(t)
+
+	var (
+		mu         sync.Mutex
+		batchSizes []int
+		succeeded  []string
+		failed     []BulkItemResult
+	)
+	started, release := make(chan struct{}, 10), make(chan struct{})
+	srv := newBlockingBulkServer(t, &batchSizes, &mu, started, release)
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	type doc struct {
+		Name string `json:"name"`
+	}
+	ctx := context.Background()
+	bi := NewBulkIndexer(ctx, cl, BulkIndexerConfig[doc]{
+		FlushDocs:     2,
+		FlushInterval: time.Hour,
+		Workers:       1,
+		QueueSize:     1,
+		OnSuccess: func(_ context.Context, op BulkOperation[doc]) {
+			mu.Lock()
+			defer mu.Unlock()
+			succeeded = append(succeeded, op.ID)
+		},
+		OnFailure: func(_ context.Context, _ BulkOperation[doc], res BulkItemResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, res)
+		},
+	})
+	add := func(ctx context.Context, id string) error {
+		return bi.Add(ctx, BulkOperation[doc]{OperationType: OpIndex, ID: id, Index: "test-index", Doc: &doc{}})
+	}
+
+	req.NoError(add(ctx, "ID-0"))
+	req.NoError(add(ctx, "ID-1"))
+	<-started
+	req.NoError(add(ctx, "ID-2"))
+	req.NoError(add(ctx, "ID-3"))
+	req.NoError(add(ctx, "ID-4"))
+
+	// the worker is busy and the queue is full
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	req.ErrorIs(add(cancelled, "ID-5"), context.Canceled)
+	bi.mu.Lock()
+	req.Len(bi.batch.ops, 1)
+	bi.mu.Unlock()
+	req.ErrorIs(bi.Close(cancelled), context.Canceled)
+
+	mu.Lock()
+	req.Len(failed, 1)
+	req.Equal("ID-4", failed[0].ID)
+	req.ErrorIs(failed[0].Error, ErrBulkItemError)
+	req.ErrorIs(failed[0].Error, context.Canceled)
+	mu.Unlock()
+
+	// the queued batches are still sent
+	close(release)
+	req.Eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(succeeded) == 4
+	}, time.Second, 10*time.Millisecond)
+	req.NoError(bi.Close(ctx))
+	req.ElementsMatch([]string{"ID-0", "ID-1", "ID-2", "ID-3"}, succeeded)
+}
+
+func TestBulkIndexerCloseUnblocksAdd(t *testing.T) {
+	req := require.New(t)
+
+	var (
+		mu         sync.Mutex
+		batchSizes []int
+		failed     []string
+	)
+	started, release := make(chan struct{}, 10), make(chan struct{})
+	srv := newBlockingBulkServer(t, &batchSizes, &mu, started, release)
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	type doc struct {
+		Name string `json:"name"`
+	}
+	ctx := context.Background()
+	bi := NewBulkIndexer(ctx, cl, BulkIndexerConfig[doc]{
+		FlushDocs:     1,
+		FlushInterval: time.Hour,
+		Workers:       1,
+		QueueSize:     1,
+		OnFailure: func(_ context.Context, op BulkOperation[doc], _ BulkItemResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, op.ID)
+		},
+	})
+
+	req.NoError(bi.Add(ctx, BulkOperation[doc]{OperationType: OpIndex, ID: "ID-0", Index: "test-index", Doc: &doc{}}))
+	<-started
+	req.NoError(bi.Add(ctx, BulkOperation[doc]{OperationType: OpIndex, ID: "ID-1", Index: "test-index", Doc: &doc{}}))
+
+	added := make(chan error, 1)
+	go func() {
+		added <- bi.Add(ctx, BulkOperation[doc]{OperationType: OpIndex, ID: "ID-2", Index: "test-index", Doc: &doc{}})
+	}()
+	req.Never(func() bool { return len(added) > 0 }, 50*time.Millisecond, 10*time.Millisecond)
+
+	closeCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	closed := make(chan error, 1)
+	go func() {
+		closed <- bi.Close(closeCtx)
+	}()
+	select {
+	case err := <-closed:
+		req.ErrorIs(err, context.DeadlineExceeded)
+	case <-time.After(time.Second):
+		req.Fail("Close blocked by Add")
+	}
+	// the blocked operation was handed over to Close
+	req.NoError(<-added)
+
+	mu.Lock()
+	req.Equal([]string{"ID-2"}, failed)
+	mu.Unlock()
+
+	close(release)
+	req.NoError(bi.Close(ctx))
+}
+
+func TestBulkIndexerRequestFailure(t *testing.T) {
+	req := require.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"type":"parse_exception","reason":"bad"},"status":400}`)
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	type doc struct {
+		Name string `json:"name"`
+	}
+	var (
+		mu     sync.Mutex
+		failed []BulkItemResult
+	)
+	ctx := context.Background()
+	bi := NewBulkIndexer(ctx, cl, BulkIndexerConfig[doc]{
+		OnFailure: func(_ context.Context, _ BulkOperation[doc], res BulkItemResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, res)
+		},
+	})
+	req.NoError(bi.Add(ctx, BulkOperation[doc]{OperationType: OpIndex, ID: "ID-0", Index: "test-index", Doc: &doc{}}))
+	req.NoError(bi.Close(ctx))
+
+	req.Len(failed, 1)
+	req.ErrorIs(failed[0].Error, ErrBulkItemError)
+	req.ErrorIs(failed[0].Error, ErrOpensearchRequestFailed)
+}
+
+func TestBulkIndexerFlusherDoesNotBlockAdd(t *testing.T) {
+	req := require.New(t)
+
+	var (
+		mu         sync.Mutex
+		batchSizes []int
+	)
+	started, release := make(chan struct{}, 10), make(chan struct{})
+	srv := newBlockingBulkServer(t, &batchSizes, &mu, started, release)
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	type doc struct {
+		Name string `json:"name"`
+	}
+	ctx := context.Background()
+	bi := NewBulkIndexer(ctx, cl, BulkIndexerConfig[doc]{
+		FlushDocs:     100,
+		FlushInterval: 10 * time.Millisecond,
+		Workers:       1,
+		QueueSize:     1,
+	})
+	pending := func() int {
+		bi.mu.Lock()
+		defer bi.mu.Unlock()
+		return len(bi.batch.ops)
+	}
+
+	req.NoError(bi.Add(ctx, BulkOperation[doc]{OperationType: OpIndex, ID: "ID-0", Index: "test-index", Doc: &doc{}}))
+	<-started
+	req.NoError(bi.Add(ctx, BulkOperation[doc]{OperationType: OpIndex, ID: "ID-1", Index: "test-index", Doc: &doc{}}))
+	req.Eventually(func() bool { return len(bi.queue) == 1 }, time.Second, time.Millisecond)
+	req.NoError(bi.Add(ctx, BulkOperation[doc]{OperationType: OpIndex, ID: "ID-2", Index: "test-index", Doc: &doc{}}))
+	// the flusher takes the batch and blocks on the full queue
+	req.Eventually(func() bool { return pending() == 0 }, time.Second, time.Millisecond)
+
+	added := make(chan error, 1)
+	go func() {
+		added <- bi.Add(ctx, BulkOperation[doc]{OperationType: OpIndex, ID: "ID-3", Index: "test-index", Doc: &doc{}})
+	}()
+	select {
+	case err := <-added:
+		req.NoError(err)
+	case <-time.After(time.Second):
+		req.Fail("Add blocked by the flusher")
+	}
+
+	close(release)
+	req.NoError(bi.Close(ctx))
+	sent := 0
+	for _, n := range batchSizes {
+		sent += n
+	}
+	req.Equal(4, sent)
+}
@@ -0,0 +1,68 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+
+	"github.com/mailstepcz/serr"
+)
+
+// bulkLoadSettings are the index settings tuned during a bulk load.
+// Nil values reset the settings to their defaults.
+type bulkLoadSettings struct {
+	Index struct {
+		RefreshInterval  *string `json:"refresh_interval"`
+		NumberOfReplicas *string `json:"number_of_replicas"`
+	} `json:"index"`
+}
+
+// BulkLoad runs the load function, e.g. one feeding a [BulkIndexer], with the index tuned for a large import.
+// Refreshes are disabled and replicas are removed for the duration of the load. Afterwards, the original settings
+// are restored, the index is refreshed and BulkLoad waits until the index is green again, at most healthTimeout
+// (zero means the OpenSearch default of 30s). The settings are restored even if the load fails or the context is cancelled.
+func BulkLoad(ctx context.Context, client *opensearchapi.Client, index string, healthTimeout time.Duration, load func(ctx context.Context) error) (err error) {
+	b, err := IndexGetSettings(ctx, client, index)
+	if err != nil {
+		return err
+	}
+	var original bulkLoadSettings
+	if err := json.Unmarshal(b, &original); err != nil {
+		return serr.Wrap("unmarshalling index settings", err, serr.String("index", index))
+	}
+
+	var tuned bulkLoadSettings
+	refreshInterval, replicas := "-1", "0"
+	tuned.Index.RefreshInterval = &refreshInterval
+	tuned.Index.NumberOfReplicas = &replicas
+	if err := bulkLoadUpdateSettings(ctx, client, index, tuned); err != nil {
+		return err
+	}
+
+	defer func() {
+		// the index shall be restored even if the context has been cancelled
+		ctx := context.WithoutCancel(ctx)
+		if restoreErr := bulkLoadUpdateSettings(ctx, client, index, original); restoreErr != nil {
+			err = errors.Join(err, serr.Wrap("restoring index settings", restoreErr))
+			return
+		}
+		if refreshErr := IndexRefresh(ctx, client, index); refreshErr != nil {
+			err = errors.Join(err, refreshErr)
+			return
+		}
+		err = errors.Join(err, IndexWaitForHealth(ctx, client, index, HealthGreen, healthTimeout))
+	}()
+
+	return load(ctx)
+}
+
+func bulkLoadUpdateSettings(ctx context.Context, client *opensearchapi.Client, index string, settings bulkLoadSettings) error {
+	b, err := json.Marshal(settings)
+	if err != nil {
+		return serr.Wrap("marshalling index settings", err)
+	}
+	return IndexUpdateSettings(ctx, client, index, b)
+}
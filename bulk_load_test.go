@@ -0,0 +1,74 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkLoad(t *testing.T) {
+	req := require.New(t)
+
+	var calls, settings []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := r.Method + " " + r.URL.Path
+		calls = append(calls, call)
+		w.Header().Set("Content-Type", "application/json")
+		switch call {
+		case "GET /orders/_settings":
+			_, _ = io.WriteString(w, `{"orders-v6":{"settings":{"index":{"number_of_replicas":"2","number_of_shards":"3"}}}}`)
+		case "PUT /orders/_settings":
+			b, _ := io.ReadAll(r.Body)
+			settings = append(settings, string(b))
+			_, _ = io.WriteString(w, `{"acknowledged":true}`)
+		case "POST /orders/_refresh":
+			_, _ = io.WriteString(w, `{"_shards":{"total":3,"successful":3,"failed":0}}`)
+		case "GET /_cluster/health/orders":
+			req.Equal("green", r.URL.Query().Get("wait_for_status"))
+			_, _ = io.WriteString(w, `{"status":"green","timed_out":false}`)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := opensearchapi.NewClient(opensearchapi.Config{Client: opensearch.Config{Addresses: []string{srv.URL}}})
+	req.NoError(err)
+
+	loaded := false
+	err = BulkLoad(context.Background(), client, "orders", 0, func(context.Context) error {
+		loaded = true
+		req.Len(settings, 1)
+		return nil
+	})
+	req.NoError(err)
+	req.True(loaded)
+	req.Equal([]string{
+		"GET /orders/_settings",
+		"PUT /orders/_settings",
+		"PUT /orders/_settings",
+		"POST /orders/_refresh",
+		"GET /_cluster/health/orders",
+	}, calls)
+	req.Len(settings, 2)
+	req.JSONEq(`{"index":{"refresh_interval":"-1","number_of_replicas":"0"}}`, settings[0])
+	req.JSONEq(`{"index":{"refresh_interval":null,"number_of_replicas":"2"}}`, settings[1])
+
+	// the settings are restored even if the load fails and cancels the context
+	calls, settings = nil, nil
+	ctx, cancel := context.WithCancel(context.Background())
+	errLoad := errors.New("load failed")
+	err = BulkLoad(ctx, client, "orders", 0, func(context.Context) error {
+		cancel()
+		return errLoad
+	})
+	req.ErrorIs(err, errLoad)
+	req.Len(settings, 2)
+	req.JSONEq(`{"index":{"refresh_interval":null,"number_of_replicas":"2"}}`, settings[1])
+	req.Equal("GET /_cluster/health/orders", calls[len(calls)-1])
+}
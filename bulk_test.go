@@ -2,8 +2,16 @@ package search
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/opensearch-project/opensearch-go/v4"
 	"github.com/stretchr/testify/require"
 )
 
@@ -144,3 +152,188 @@ func Test_buildBulkBody_versionedDocument(t *testing.T) {
 	req.NoError(err)
 	req.Equal(want, b.Bytes())
 }
+
+func TestBulkRetry(t *testing.T) {
+	req := require.New(t)
+
+	var requests []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lines := strings.Split(strings.TrimSpace(readAll(t, r.Body)), "\n")
+		requests = append(requests, len(lines)/2)
+
+		// the first attempt rejects ID-2 and ID-3, then ID-3 keeps being rejected
+		var items []string
+		for i := 0; i < len(lines); i += 2 {
+			switch {
+			case strings.Contains(lines[i], `"ID-3"`):
+				items = append(items, `{"index":{"_id":"ID-3","status":429,"error":{"type":"es_rejected_execution_exception","reason":"rejected"}}}`)
+			case strings.Contains(lines[i], `"ID-2"`) && len(requests) == 1:
+				items = append(items, `{"index":{"_id":"ID-2","status":503,"error":{"type":"unavailable_shards_exception","reason":"unavailable"}}}`)
+			case strings.Contains(lines[i], `"ID-4"`):
+				items = append(items, `{"index":{"_id":"ID-4","status":409,"error":{"type":"version_conflict_engine_exception","reason":"conflict"}}}`)
+			default:
+				items = append(items, `{"index":{"status":201}}`)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"errors":true,"items":[`+strings.Join(items, ",")+`]}`)
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	type doc struct {
+		Name string `json:"name"`
+	}
+	var ops []BulkOperation[doc]
+	for i := 1; i <= 4; i++ {
+		id := fmt.Sprintf("ID-%d", i)
+		ops = append(ops, BulkOperation[doc]{OperationType: OpIndex, ID: id, Index: "test-index", Doc: &doc{Name: id}})
+	}
+
+	res, err := Bulk(context.Background(), cl, ops, WithBulkRetry(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+	req.NoError(err)
+	req.Equal([]int{4, 2, 1}, requests)
+	req.Len(res.Items, 2)
+	req.Equal("ID-3", res.Items[0].ID)
+	req.ErrorIs(res.Items[0].Error, ErrBulkItemRetriable)
+	req.Equal("ID-4", res.Items[1].ID)
+	req.ErrorIs(res.Items[1].Error, ErrDocumentHasNewerVersion)
+}
+
+func TestBulkRetryRequest(t *testing.T) {
+	req := require.New(t)
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests < 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = io.WriteString(w, `{"error":{"type":"rejected_execution_exception","reason":"rejected"},"status":429}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"errors":false,"items":[{"index":{"_id":"ID-1","status":201}}]}`)
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	type doc struct {
+		Name string `json:"name"`
+	}
+	ops := []BulkOperation[doc]{{OperationType: OpIndex, ID: "ID-1", Index: "test-index", Doc: &doc{Name: "a"}}}
+
+	res, err := Bulk(context.Background(), cl, ops, WithBulkRetry(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+	req.NoError(err)
+	req.Equal(3, requests)
+	req.Empty(res.Items)
+
+	requests = 0
+	_, err = Bulk(context.Background(), cl, ops, WithBulkRetry(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+	req.ErrorIs(err, ErrBulkItemRetriable)
+	req.Equal(2, requests)
+
+	requests = 0
+	_, err = Bulk(context.Background(), cl, ops)
+	req.ErrorIs(err, ErrBulkItemRetriable)
+	req.Equal(1, requests)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	req := require.New(t)
+
+	p := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	for range 100 {
+		req.LessOrEqual(p.backoff(0), 10*time.Millisecond)
+		req.LessOrEqual(p.backoff(2), 40*time.Millisecond)
+		req.LessOrEqual(p.backoff(10), 50*time.Millisecond)
+		req.LessOrEqual(p.backoff(100), 50*time.Millisecond)
+		req.GreaterOrEqual(p.backoff(100), time.Duration(0))
+	}
+	req.Equal(DefaultRetryMaxAttempts, p.maxAttempts())
+}
+
+func readAll(t *testing.T, r io.Reader) string {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func Test_buildBulkBody_update(t *testing.T) {
+	type doc struct {
+		Name     string `json:"name,omitempty"`
+		Quantity int    `json:"quantity,omitempty"`
+	}
+
+	tests := []struct {
+		name string
+		op   BulkOperation[doc]
+		want string
+	}{{
+		name: "partial doc",
+		op:   BulkOperation[doc]{OperationType: OpUpdate, ID: "ID-1", Index: "test-index", Doc: &doc{Quantity: 5}},
+		want: `{"update":{"_id":"ID-1","_index":"test-index"}}` + "\n" +
+			`{"doc":{"quantity":5}}` + "\n",
+	}, {
+		name: "doc as upsert",
+		op:   BulkOperation[doc]{OperationType: OpUpdate, ID: "ID-1", Index: "test-index", Doc: &doc{Quantity: 5}, DocAsUpsert: true, RetryOnConflict: 3},
+		want: `{"update":{"_id":"ID-1","_index":"test-index","retry_on_conflict":3}}` + "\n" +
+			`{"doc":{"quantity":5},"doc_as_upsert":true}` + "\n",
+	}, {
+		name: "explicit upsert",
+		op:   BulkOperation[doc]{OperationType: OpUpdate, ID: "ID-1", Index: "test-index", Doc: &doc{Quantity: 5}, Upsert: &doc{Name: "new", Quantity: 5}},
+		want: `{"update":{"_id":"ID-1","_index":"test-index"}}` + "\n" +
+			`{"doc":{"quantity":5},"upsert":{"name":"new","quantity":5}}` + "\n",
+	}, {
+		name: "script",
+		op: BulkOperation[doc]{
+			OperationType: OpUpdate, ID: "ID-1", Index: "test-index",
+			Script: &Script{Source: "ctx._source.quantity += params.n", Lang: "painless", Params: map[string]any{"n": 2}},
+			Upsert: &doc{Quantity: 2},
+		},
+		want: `{"update":{"_id":"ID-1","_index":"test-index"}}` + "\n" +
+			`{"script":{"source":"ctx._source.quantity += params.n","lang":"painless","params":{"n":2}},"upsert":{"quantity":2}}` + "\n",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+			var b bytes.Buffer
+			err := buildBulkBody([]BulkOperation[doc]{tt.op}, &b)
+
+			req.NoError(err)
+			req.Equal(tt.want, b.String())
+		})
+	}
+}
+
+func Test_buildBulkBody_ifSeqNo(t *testing.T) {
+	req := require.New(t)
+
+	seqNo, primaryTerm := 12, 3
+	var b bytes.Buffer
+	err := buildBulkBody([]BulkOperation[versionedDoc]{{OperationType: OpDelete, ID: "ID-1", Index: "test-index", IfSeqNo: &seqNo, IfPrimaryTerm: &primaryTerm}}, &b)
+	req.NoError(err)
+	req.Equal(`{"delete":{"_id":"ID-1","_index":"test-index","if_primary_term":3,"if_seq_no":12}}`+"\n", b.String())
+}
+
+func Test_buildBulkBody_updateWithoutDoc(t *testing.T) {
+	req := require.New(t)
+
+	var b bytes.Buffer
+	err := buildBulkBody([]BulkOperation[versionedDoc]{{OperationType: OpUpdate, ID: "ID-1", Index: "test-index"}}, &b)
+	req.ErrorIs(err, ErrOpensearchBadRequest)
+}
+
+func Test_buildBulkBody_updateVersionedDocument(t *testing.T) {
+	req := require.New(t)
+
+	var b bytes.Buffer
+	err := buildBulkBody([]BulkOperation[versionedDoc]{{OperationType: OpUpdate, ID: "ID-1", Index: "test-index", Doc: &versionedDoc{Name: "x"}}}, &b)
+	req.NoError(err)
+	req.Equal(`{"update":{"_id":"ID-1","_index":"test-index"}}`+"\n"+`{"doc":{"id":"","name":"x"}}`+"\n", b.String())
+}
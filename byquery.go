@@ -0,0 +1,207 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+
+	"github.com/mailstepcz/serr"
+)
+
+// ErrByQueryFailed signifies that some documents of a delete-by-query or update-by-query failed.
+// The response with the failures is returned along with it.
+var ErrByQueryFailed = errors.New("by-query operation failed")
+
+// ByQueryResponse is the outcome of a delete-by-query or update-by-query.
+type ByQueryResponse struct {
+	Took             int               `json:"took"`
+	TimedOut         bool              `json:"timed_out"`
+	Total            int               `json:"total"`
+	Updated          int               `json:"updated"`
+	Deleted          int               `json:"deleted"`
+	Batches          int               `json:"batches"`
+	VersionConflicts int               `json:"version_conflicts"`
+	Noops            int               `json:"noops"`
+	Failures         []json.RawMessage `json:"failures"`
+	Task             TaskID            `json:"task"`
+}
+
+// ByQueryOption allows customization of delete-by-query and update-by-query.
+type ByQueryOption func(*byQueryConfig)
+
+type byQueryConfig struct {
+	conflicts         string
+	slices            any
+	requestsPerSecond *int
+	refresh           *bool
+	maxDocs           *int
+	scrollSize        *int
+}
+
+// WithConflictsProceed counts version conflicts instead of aborting on the first one.
+func WithConflictsProceed() ByQueryOption {
+	return func(c *byQueryConfig) {
+		c.conflicts = "proceed"
+	}
+}
+
+// WithSlices splits the operation into n slices processed in parallel.
+func WithSlices(n int) ByQueryOption {
+	return func(c *byQueryConfig) {
+		c.slices = n
+	}
+}
+
+// WithAutoSlices lets OpenSearch choose the number of slices, usually one per shard.
+func WithAutoSlices() ByQueryOption {
+	return func(c *byQueryConfig) {
+		c.slices = "auto"
+	}
+}
+
+// WithRequestsPerSecond throttles the operation to n documents per second; -1 disables throttling.
+func WithRequestsPerSecond(n int) ByQueryOption {
+	return func(c *byQueryConfig) {
+		c.requestsPerSecond = &n
+	}
+}
+
+// WithByQueryRefresh refreshes the affected shards once the operation completes.
+func WithByQueryRefresh() ByQueryOption {
+	return func(c *byQueryConfig) {
+		refresh := true
+		c.refresh = &refresh
+	}
+}
+
+// WithMaxDocs limits the number of processed documents.
+func WithMaxDocs(n int) ByQueryOption {
+	return func(c *byQueryConfig) {
+		c.maxDocs = &n
+	}
+}
+
+// WithScrollSize sets the size of the batches the documents are processed in.
+func WithScrollSize(n int) ByQueryOption {
+	return func(c *byQueryConfig) {
+		c.scrollSize = &n
+	}
+}
+
+// DeleteByQuery deletes the documents matching the expression and waits for the completion.
+func DeleteByQuery(ctx context.Context, cl *opensearch.Client, index string, expr Expr, opts ...ByQueryOption) (*ByQueryResponse, error) {
+	return deleteByQuery(ctx, cl, index, expr, true, opts...)
+}
+
+// DeleteByQueryAsync starts deleting the documents matching the expression in the background.
+func DeleteByQueryAsync(ctx context.Context, cl *opensearch.Client, index string, expr Expr, opts ...ByQueryOption) (*Task, error) {
+	res, err := deleteByQuery(ctx, cl, index, expr, false, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewTask(cl, res.Task), nil
+}
+
+// UpdateByQuery updates the documents matching the expression by the script and waits for the completion.
+// If the script is nil, the documents are reindexed in place, e.g. to pick up a mapping change.
+func UpdateByQuery(ctx context.Context, cl *opensearch.Client, index string, expr Expr, script *Script, opts ...ByQueryOption) (*ByQueryResponse, error) {
+	return updateByQuery(ctx, cl, index, expr, script, true, opts...)
+}
+
+// UpdateByQueryAsync starts updating the documents matching the expression in the background.
+func UpdateByQueryAsync(ctx context.Context, cl *opensearch.Client, index string, expr Expr, script *Script, opts ...ByQueryOption) (*Task, error) {
+	res, err := updateByQuery(ctx, cl, index, expr, script, false, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewTask(cl, res.Task), nil
+}
+
+func deleteByQuery(ctx context.Context, cl *opensearch.Client, index string, expr Expr, wait bool, opts ...ByQueryOption) (*ByQueryResponse, error) {
+	b, err := byQueryBody(expr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var c byQueryConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	req := opensearchapi.DocumentDeleteByQueryReq{
+		Indices: []string{index},
+		Body:    bytes.NewReader(b),
+		Params: opensearchapi.DocumentDeleteByQueryParams{
+			Conflicts:         c.conflicts,
+			Slices:            c.slices,
+			RequestsPerSecond: c.requestsPerSecond,
+			Refresh:           c.refresh,
+			MaxDocs:           c.maxDocs,
+			ScrollSize:        c.scrollSize,
+			WaitForCompletion: &wait,
+		},
+	}
+	return doByQuery(ctx, cl, req, index)
+}
+
+func updateByQuery(ctx context.Context, cl *opensearch.Client, index string, expr Expr, script *Script, wait bool, opts ...ByQueryOption) (*ByQueryResponse, error) {
+	b, err := byQueryBody(expr, script)
+	if err != nil {
+		return nil, err
+	}
+
+	var c byQueryConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	req := opensearchapi.UpdateByQueryReq{
+		Indices: []string{index},
+		Body:    bytes.NewReader(b),
+		Params: opensearchapi.UpdateByQueryParams{
+			Conflicts:         c.conflicts,
+			Slices:            c.slices,
+			RequestsPerSecond: c.requestsPerSecond,
+			Refresh:           c.refresh,
+			MaxDocs:           c.maxDocs,
+			ScrollSize:        c.scrollSize,
+			WaitForCompletion: &wait,
+		},
+	}
+	return doByQuery(ctx, cl, req, index)
+}
+
+func byQueryBody(expr Expr, script *Script) ([]byte, error) {
+	query, err := buildQuery(expr, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Query  searchBool `json:"query"`
+		Script *Script    `json:"script,omitempty"`
+	}{query.Query, script})
+}
+
+func doByQuery(ctx context.Context, cl *opensearch.Client, req opensearch.Request, index string) (*ByQueryResponse, error) {
+	var osResp ByQueryResponse
+	resp, err := cl.Do(ctx, req, &osResp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		if resp.StatusCode == http.StatusConflict {
+			return nil, errors.Join(ErrDocumentHasNewerVersion, osError(resp))
+		}
+		return nil, osError(resp)
+	}
+	if len(osResp.Failures) > 0 {
+		return &osResp, serr.Wrap("", ErrByQueryFailed,
+			serr.String("index", index),
+			serr.Int("failures", len(osResp.Failures)),
+			serr.String("firstFailure", string(osResp.Failures[0])))
+	}
+	return &osResp, nil
+}
@@ -0,0 +1,103 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteByQuery(t *testing.T) {
+	req := require.New(t)
+
+	var path string
+	var query url.Values
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		query = r.URL.Query()
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		if query.Get("wait_for_completion") == "false" {
+			_, _ = io.WriteString(w, `{"task":"node-1:42"}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"took":12,"total":3,"deleted":2,"batches":1,"version_conflicts":1,"failures":[]}`)
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	expr := Eq[string]{Ident: "tenant", Value: "X"}
+	res, err := DeleteByQuery(context.Background(), cl, "orders", expr,
+		WithConflictsProceed(), WithAutoSlices(), WithRequestsPerSecond(500), WithByQueryRefresh())
+	req.NoError(err)
+	req.Equal(2, res.Deleted)
+	req.Equal(1, res.VersionConflicts)
+	req.Equal("/orders/_delete_by_query", path)
+	req.Equal("proceed", query.Get("conflicts"))
+	req.Equal("auto", query.Get("slices"))
+	req.Equal("500", query.Get("requests_per_second"))
+	req.Equal("true", query.Get("refresh"))
+	req.Equal("true", query.Get("wait_for_completion"))
+	req.Equal(map[string]any{"query": map[string]any{"bool": map[string]any{"must": []any{
+		map[string]any{"term": map[string]any{"tenant": "X"}},
+	}}}}, body)
+
+	task, err := DeleteByQueryAsync(context.Background(), cl, "orders", expr, WithSlices(4))
+	req.NoError(err)
+	req.Equal(TaskID("node-1:42"), task.ID)
+	req.Equal("4", query.Get("slices"))
+}
+
+func TestUpdateByQuery(t *testing.T) {
+	req := require.New(t)
+
+	var path string
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"total":2,"updated":1,"failures":[{"id":"2","status":400,"cause":{"type":"mapper_parsing_exception"}}]}`)
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	res, err := UpdateByQuery(context.Background(), cl, "orders", Eq[string]{Ident: "warehouse", Value: "Z"}, &Script{
+		Source: "ctx._source.flag = params.flag",
+		Params: map[string]any{"flag": true},
+	})
+	req.ErrorIs(err, ErrByQueryFailed)
+	req.NotNil(res)
+	req.Equal(1, res.Updated)
+	req.Len(res.Failures, 1)
+	req.Equal("/orders/_update_by_query", path)
+	req.Equal(map[string]any{"source": "ctx._source.flag = params.flag", "params": map[string]any{"flag": true}}, body["script"])
+}
+
+func TestByQueryConflict(t *testing.T) {
+	req := require.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		_, _ = io.WriteString(w, `{"total":2,"deleted":0,"version_conflicts":1,"failures":[{"status":409}]}`)
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	_, err = DeleteByQuery(context.Background(), cl, "orders", Eq[string]{Ident: "tenant", Value: "X"})
+	req.ErrorIs(err, ErrDocumentHasNewerVersion)
+}
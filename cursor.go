@@ -0,0 +1,194 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+
+	"github.com/mailstepcz/serr"
+)
+
+// DefaultCursorTiebreaker is the field appended to the sort of cursor-based searches unless the pagination sets another one.
+const DefaultCursorTiebreaker = "_id"
+
+var (
+	// ErrInvalidCursor signifies that a page cursor is malformed or its signature doesn't match.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrCursorQueryMismatch signifies that a page cursor was issued for a different query.
+	ErrCursorQueryMismatch = errors.New("cursor doesn't match the query")
+)
+
+// CursorPagination contains the page size and the cursor for cursor-based searches.
+// The cursor is empty for the first page. If SigningKey is set, cursors are signed with HMAC-SHA256
+// and cursors with a wrong signature are rejected.
+// Tiebreaker is a field unique per document appended to the order to make paging stable; it defaults to
+// [DefaultCursorTiebreaker]. Sorting by _id requires fielddata, so a keyword copy of the ID in the source
+// is cheaper. [PitTiebreaker] can't be used without a point in time.
+type CursorPagination struct {
+	Size       int
+	Cursor     string
+	SigningKey []byte
+	Tiebreaker string
+}
+
+// Page is a page of search results.
+// TotalExact is false if Total is only a lower bound, see [SearchResponse].
+// NextCursor is empty if there are no more results.
+type Page[T any] struct {
+	Docs       []IDedDocument[T]
+	Total      int
+	TotalExact bool
+	NextCursor string
+}
+
+// SearchPage searches for documents page by page using search_after.
+// The orderBy argument has the same format as in [Search]; the tiebreaker field of the pagination is appended to it.
+// Unlike [Search], the paging is not limited by [MaxResultWindow].
+func SearchPage[T any](ctx context.Context, cl *opensearch.Client, index string, expr Expr, orderBy string, pag CursorPagination, opts ...SearchOption) (*Page[T], error) {
+	query, err := buildQuery(expr, orderBy, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(query)
+	}
+	tiebreaker := pag.Tiebreaker
+	if tiebreaker == "" {
+		tiebreaker = DefaultCursorTiebreaker
+	}
+	query.addTiebreaker(tiebreaker)
+
+	fingerprint, err := queryFingerprint(index, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if pag.Cursor != "" {
+		c, err := decodeCursor(pag.Cursor, pag.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+		if c.Fingerprint != fingerprint {
+			return nil, serr.Wrap("", ErrCursorQueryMismatch, serr.String("index", index))
+		}
+		query.SearchAfter = c.SearchAfter
+	}
+	query.Size = &pag.Size
+
+	b, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	req := opensearchapi.SearchReq{
+		Indices: []string{index},
+		Body:    bytes.NewReader(b),
+		Params:  searchParams(query),
+	}
+	var osResp searchResp
+	resp, err := cl.Do(ctx, req, &osResp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, osError(resp)
+	}
+
+	hits := osResp.Hits.Hits
+	docs, err := mapDocs[T](hits)
+	if err != nil {
+		return nil, err
+	}
+
+	page := Page[T]{
+		Docs:       docs,
+		Total:      osResp.Hits.Total.Value,
+		TotalExact: osResp.Hits.Total.Relation == TotalRelationEq,
+	}
+	if len(hits) > 0 && len(hits) == pag.Size {
+		page.NextCursor, err = encodeCursor(cursor{
+			SearchAfter: docs[len(docs)-1].Sort,
+			Fingerprint: fingerprint,
+		}, pag.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &page, nil
+}
+
+type cursor struct {
+	SearchAfter []any  `json:"a"`
+	Fingerprint string `json:"q"`
+}
+
+// queryFingerprint identifies the index, query and order, i.e. everything a cursor depends on.
+func queryFingerprint(index string, q *searchQuery) (string, error) {
+	b, err := json.Marshal(struct {
+		Index string     `json:"index"`
+		Query searchBool `json:"query"`
+		Sort  Sort       `json:"sort"`
+	}{index, q.Query, q.Sort})
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:12]), nil
+}
+
+func encodeCursor(c cursor, key []byte) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", serr.Wrap("marshalling cursor", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	if len(key) > 0 {
+		token += "." + base64.RawURLEncoding.EncodeToString(cursorSignature(b, key))
+	}
+	return token, nil
+}
+
+func decodeCursor(token string, key []byte) (*cursor, error) {
+	payload, sig, signed := strings.Cut(token, ".")
+	if signed != (len(key) > 0) {
+		return nil, serr.Wrap("unexpected cursor signature", ErrInvalidCursor)
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, serr.Wrap("decoding cursor", errors.Join(ErrInvalidCursor, err))
+	}
+	if signed {
+		s, err := base64.RawURLEncoding.DecodeString(sig)
+		if err != nil {
+			return nil, serr.Wrap("decoding cursor signature", errors.Join(ErrInvalidCursor, err))
+		}
+		if !hmac.Equal(s, cursorSignature(b, key)) {
+			return nil, serr.Wrap("cursor signature mismatch", ErrInvalidCursor)
+		}
+	}
+
+	var c cursor
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber() // keep sort values of long fields exact
+	if err := dec.Decode(&c); err != nil {
+		return nil, serr.Wrap("unmarshalling cursor", errors.Join(ErrInvalidCursor, err))
+	}
+	return &c, nil
+}
+
+func cursorSignature(payload, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
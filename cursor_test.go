@@ -0,0 +1,77 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		key  []byte
+	}{
+		{name: "unsigned", key: nil},
+		{name: "signed", key: []byte("secret")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			token, err := encodeCursor(cursor{SearchAfter: []any{1790812800000, "ID-1"}, Fingerprint: "abcd"}, tt.key)
+			req.NoError(err)
+			req.NotContains(token, "=")
+
+			c, err := decodeCursor(token, tt.key)
+			req.NoError(err)
+			req.Equal("abcd", c.Fingerprint)
+			req.Equal([]any{json.Number("1790812800000"), "ID-1"}, c.SearchAfter)
+		})
+	}
+}
+
+func TestCursorSignature(t *testing.T) {
+	req := require.New(t)
+
+	token, err := encodeCursor(cursor{SearchAfter: []any{1}, Fingerprint: "abcd"}, []byte("secret"))
+	req.NoError(err)
+
+	_, err = decodeCursor(token, []byte("other"))
+	req.ErrorIs(err, ErrInvalidCursor)
+
+	_, err = decodeCursor(token, nil)
+	req.ErrorIs(err, ErrInvalidCursor)
+
+	unsigned, err := encodeCursor(cursor{SearchAfter: []any{1}, Fingerprint: "abcd"}, nil)
+	req.NoError(err)
+	_, err = decodeCursor(unsigned, []byte("secret"))
+	req.ErrorIs(err, ErrInvalidCursor)
+
+	_, err = decodeCursor("!!!", nil)
+	req.ErrorIs(err, ErrInvalidCursor)
+}
+
+func TestSearchPageQueryMismatch(t *testing.T) {
+	req := require.New(t)
+
+	query, err := buildQuery(Eq[string]{Ident: "warehouse", Value: "W1"}, "-createdAt", nil)
+	req.NoError(err)
+	query.addTiebreaker(DefaultCursorTiebreaker)
+	fingerprint, err := queryFingerprint("orders", query)
+	req.NoError(err)
+
+	token, err := encodeCursor(cursor{SearchAfter: []any{1}, Fingerprint: fingerprint}, nil)
+	req.NoError(err)
+
+	_, err = SearchPage[struct{}](context.Background(), nil, "orders", Eq[string]{Ident: "warehouse", Value: "W2"}, "-createdAt", CursorPagination{Size: 10, Cursor: token})
+	req.ErrorIs(err, ErrCursorQueryMismatch)
+
+	_, err = SearchPage[struct{}](context.Background(), nil, "orders", Eq[string]{Ident: "warehouse", Value: "W1"}, "createdAt", CursorPagination{Size: 10, Cursor: token})
+	req.ErrorIs(err, ErrCursorQueryMismatch)
+
+	_, err = SearchPage[struct{}](context.Background(), nil, "orders", Eq[string]{Ident: "warehouse", Value: "W1"}, "-createdAt", CursorPagination{Size: 10, Cursor: token, Tiebreaker: "orderNumber"})
+	req.ErrorIs(err, ErrCursorQueryMismatch)
+}
@@ -190,37 +190,249 @@ func (e And) Idents() []string {
 func (e And) Map(fl ExprFlavour) (any, error) {
 	switch fl {
 	case DocDB:
-		m := Map{Pairs: make([]KVPair, 0, len(e.Exprs))}
+		l, err := docDBMaps(e.Exprs)
+		if err != nil {
+			return nil, err
+		}
+		m := Map{Pairs: make([]KVPair, 0, len(l))}
+		keys := make(map[string]struct{}, len(l))
+		for _, em := range l {
+			for _, p := range em.Pairs {
+				if _, ok := keys[p.Key]; ok {
+					// merging would produce duplicate keys, fall back to an explicit conjunction
+					return Map{Pairs: []KVPair{{"$and", l}}}, nil
+				}
+				keys[p.Key] = struct{}{}
+				m.Pairs = append(m.Pairs, p)
+			}
+		}
+		return m, nil
+	case OpenSearch:
+		l := make([]Map, 0, len(e.Exprs))
 		for _, e := range e.Exprs {
-			em, err := e.Map(fl)
+			em, err := osClause(e)
 			if err != nil {
 				return nil, err
 			}
-			if em2, ok := em.(Map); ok {
-				m.Pairs = append(m.Pairs, em2.Pairs...)
-			} else {
-				return nil, serr.New("expected map", serr.Any("expr", em))
-			}
+			l = append(l, em)
 		}
-		return m, nil
+		return l, nil
+	}
+	panic("unknown expression flavour: " + fl.String())
+}
+
+// Or is an AST node for disjunction. An empty disjunction matches no documents.
+type Or struct {
+	Exprs []Expr
+}
+
+// Idents returns all the identifiers in the expression.
+func (e Or) Idents() []string {
+	var idents []string
+	for _, el := range e.Exprs {
+		idents = append(idents, el.Idents()...)
+	}
+	return idents
+}
+
+// Map returns the query map corresponding to the expression.
+func (e Or) Map(fl ExprFlavour) (any, error) {
+	switch fl {
+	case DocDB:
+		if len(e.Exprs) == 0 {
+			// $or rejects an empty list; every document has an _id
+			return Map{Pairs: []KVPair{
+				{"_id", Map{Pairs: []KVPair{{"$exists", false}}}},
+			}}, nil
+		}
+		l, err := docDBMaps(e.Exprs)
+		if err != nil {
+			return nil, err
+		}
+		return Map{Pairs: []KVPair{
+			{"$or", l},
+		}}, nil
 	case OpenSearch:
 		l := make([]Map, 0, len(e.Exprs))
 		for _, e := range e.Exprs {
-			em, err := e.Map(fl)
+			em, err := osClause(e)
 			if err != nil {
 				return nil, err
 			}
-			if em2, ok := em.(Map); ok {
-				l = append(l, em2)
-			} else {
-				return nil, serr.New("expected map", serr.Any("expr", em))
+			l = append(l, em)
+		}
+		return Map{Pairs: []KVPair{
+			{"bool", Map{Pairs: []KVPair{
+				{"should", l},
+				{"minimum_should_match", 1},
+			}}},
+		}}, nil
+	}
+	panic("unknown expression flavour: " + fl.String())
+}
+
+// Not is an AST node for negation.
+type Not struct {
+	Expr Expr
+}
+
+// Idents returns all the identifiers in the expression.
+func (e Not) Idents() []string {
+	return e.Expr.Idents()
+}
+
+// Map returns the query map corresponding to the expression.
+func (e Not) Map(fl ExprFlavour) (any, error) {
+	switch fl {
+	case DocDB:
+		em, err := docDBMap(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		// a single field with operators can be negated in place
+		if len(em.Pairs) == 1 && !isDocDBOperator(em.Pairs[0].Key) {
+			if ops, ok := em.Pairs[0].Value.(Map); ok && len(ops.Pairs) > 0 && isDocDBOperator(ops.Pairs[0].Key) {
+				return Map{Pairs: []KVPair{
+					{em.Pairs[0].Key, Map{Pairs: []KVPair{
+						{"$not", ops},
+					}}},
+				}}, nil
 			}
 		}
-		return l, nil
+		return Map{Pairs: []KVPair{
+			{"$nor", []Map{em}},
+		}}, nil
+	case OpenSearch:
+		em, err := osClause(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return Map{Pairs: []KVPair{
+			{"bool", Map{Pairs: []KVPair{
+				{"must_not", []Map{em}},
+			}}},
+		}}, nil
 	}
 	panic("unknown expression flavour: " + fl.String())
 }
 
+// Filter is an AST node marking the expression as non-scoring.
+// Within a search query the expression is put into the filter context, hence it doesn't contribute
+// to relevance and OpenSearch can cache it.
+type Filter struct {
+	Expr Expr
+}
+
+// Idents returns all the identifiers in the expression.
+func (e Filter) Idents() []string {
+	return e.Expr.Idents()
+}
+
+// Map returns the query map corresponding to the expression.
+func (e Filter) Map(fl ExprFlavour) (any, error) {
+	switch fl {
+	case DocDB:
+		return e.Expr.Map(fl)
+	case OpenSearch:
+		l, err := osClauses(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return Map{Pairs: []KVPair{
+			{"bool", Map{Pairs: []KVPair{
+				{"filter", l},
+			}}},
+		}}, nil
+	}
+	panic("unknown expression flavour: " + fl.String())
+}
+
+// osBool splits the expression into scoring and non-scoring OpenSearch clauses.
+// Filters found at the top level or within top-level conjunctions go into the filter context.
+func osBool(e Expr) (must, filter []Map, err error) {
+	switch e := e.(type) {
+	case Filter:
+		filter, err = osClauses(e.Expr)
+		return nil, filter, err
+	case And:
+		must = make([]Map, 0, len(e.Exprs))
+		for _, e := range e.Exprs {
+			m, f, err := osBool(e)
+			if err != nil {
+				return nil, nil, err
+			}
+			must = append(must, m...)
+			filter = append(filter, f...)
+		}
+		return must, filter, nil
+	}
+	must, err = osClauses(e)
+	return must, nil, err
+}
+
+// osClause maps the expression into a single OpenSearch query clause.
+// A list of clauses (as returned by [And]) is wrapped into a bool query.
+func osClause(e Expr) (Map, error) {
+	em, err := e.Map(OpenSearch)
+	if err != nil {
+		return Map{}, err
+	}
+	switch em := em.(type) {
+	case Map:
+		return em, nil
+	case []Map:
+		return Map{Pairs: []KVPair{
+			{"bool", Map{Pairs: []KVPair{
+				{"must", em},
+			}}},
+		}}, nil
+	}
+	return Map{}, serr.New("expected map", serr.Any("expr", em))
+}
+
+// osClauses maps the expression into a list of OpenSearch query clauses combined by conjunction.
+// Unlike [osClause], the clauses of a top-level [And] are kept flat.
+func osClauses(e Expr) ([]Map, error) {
+	em, err := e.Map(OpenSearch)
+	if err != nil {
+		return nil, err
+	}
+	switch em := em.(type) {
+	case Map:
+		return []Map{em}, nil // the "must" attribute shall be an array
+	case []Map:
+		return em, nil
+	}
+	return nil, fmt.Errorf("%w %T", ErrOpensearchBadRequest, em)
+}
+
+func docDBMap(e Expr) (Map, error) {
+	em, err := e.Map(DocDB)
+	if err != nil {
+		return Map{}, err
+	}
+	if em2, ok := em.(Map); ok {
+		return em2, nil
+	}
+	return Map{}, serr.New("expected map", serr.Any("expr", em))
+}
+
+func docDBMaps(exprs []Expr) ([]Map, error) {
+	l := make([]Map, 0, len(exprs))
+	for _, e := range exprs {
+		em, err := docDBMap(e)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, em)
+	}
+	return l, nil
+}
+
+func isDocDBOperator(key string) bool {
+	return len(key) > 0 && key[0] == '$'
+}
+
 // Neq is an AST node for inequality.
 type Neq[T any] struct {
 	Ident string
@@ -339,6 +551,9 @@ var (
 	_ Expr = new(Eq[string])
 	_ Expr = new(Neq[string])
 	_ Expr = new(And)
+	_ Expr = new(Or)
+	_ Expr = new(Not)
+	_ Expr = new(Filter)
 	_ Expr = new(Interval[string])
 	_ Expr = new(Match)
 	_ Expr = new(Wildcard)
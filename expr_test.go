@@ -1,6 +1,7 @@
 package search
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -118,3 +119,130 @@ func TestOpenSearachNotExists(t *testing.T) {
 		}}},
 	}}, m)
 }
+
+func TestOpensearchOr(t *testing.T) {
+	req := require.New(t)
+
+	e := Or{Exprs: []Expr{Eq[string]{Ident: "status", Value: "shipped"}, Eq[string]{Ident: "status", Value: "delivered"}}}
+	m, err := e.Map(OpenSearch)
+	req.NoError(err)
+	req.Equal(Map{[]KVPair{
+		{"bool", Map{[]KVPair{
+			{"should", []Map{
+				{[]KVPair{{"term", Map{[]KVPair{{"status", "shipped"}}}}}},
+				{[]KVPair{{"term", Map{[]KVPair{{"status", "delivered"}}}}}},
+			}},
+			{"minimum_should_match", 1},
+		}}},
+	}}, m)
+}
+
+func TestOpensearchNot(t *testing.T) {
+	req := require.New(t)
+
+	e := Not{Expr: And{Exprs: []Expr{Eq[int]{Ident: "a", Value: 1}, Eq[int]{Ident: "b", Value: 2}}}}
+	m, err := e.Map(OpenSearch)
+	req.NoError(err)
+	req.Equal(Map{[]KVPair{
+		{"bool", Map{[]KVPair{
+			{"must_not", []Map{
+				{[]KVPair{{"bool", Map{[]KVPair{
+					{"must", []Map{
+						{[]KVPair{{"term", Map{[]KVPair{{"a", 1}}}}}},
+						{[]KVPair{{"term", Map{[]KVPair{{"b", 2}}}}}},
+					}},
+				}}}}},
+			}},
+		}}},
+	}}, m)
+}
+
+func TestOpensearchNested(t *testing.T) {
+	req := require.New(t)
+
+	e := And{Exprs: []Expr{
+		Eq[string]{Ident: "warehouse", Value: "W1"},
+		Or{Exprs: []Expr{
+			Eq[string]{Ident: "status", Value: "shipped"},
+			Not{Expr: Exists{Ident: "deletedAt"}},
+		}},
+	}}
+	m, err := e.Map(OpenSearch)
+	req.NoError(err)
+
+	l, ok := m.([]Map)
+	req.True(ok)
+	b, err := json.Marshal(l)
+	req.NoError(err)
+	req.JSONEq(`[
+		{"term":{"warehouse":"W1"}},
+		{"bool":{"should":[
+			{"term":{"status":"shipped"}},
+			{"bool":{"must_not":[{"exists":{"field":"deletedAt"}}]}}
+		],"minimum_should_match":1}}
+	]`, string(b))
+}
+
+func TestDocDBOr(t *testing.T) {
+	req := require.New(t)
+
+	e := Or{Exprs: []Expr{Eq[int]{Ident: "a", Value: 1234}, Eq[int]{Ident: "b", Value: 5678}}}
+	m, err := e.Map(DocDB)
+	req.NoError(err)
+	req.Equal(Map{[]KVPair{{"$or", []Map{
+		{[]KVPair{{"a", 1234}}},
+		{[]KVPair{{"b", 5678}}},
+	}}}}, m)
+
+	m, err = Or{}.Map(DocDB)
+	req.NoError(err)
+	req.Equal(Map{[]KVPair{{"_id", Map{[]KVPair{{"$exists", false}}}}}}, m)
+}
+
+func TestDocDBNot(t *testing.T) {
+	req := require.New(t)
+
+	from := 10
+	e := Not{Expr: Interval[int]{Ident: "a", From: &from, FromInclusive: true}}
+	m, err := e.Map(DocDB)
+	req.NoError(err)
+	req.Equal(Map{[]KVPair{{"a", Map{[]KVPair{{"$not", Map{[]KVPair{{"$gte", 10}}}}}}}}}, m)
+
+	e = Not{Expr: Eq[int]{Ident: "a", Value: 1234}}
+	m, err = e.Map(DocDB)
+	req.NoError(err)
+	req.Equal(Map{[]KVPair{{"$nor", []Map{{[]KVPair{{"a", 1234}}}}}}}, m)
+}
+
+func TestDocDBAndDuplicateKeys(t *testing.T) {
+	req := require.New(t)
+
+	e := And{Exprs: []Expr{
+		Or{Exprs: []Expr{Eq[int]{Ident: "a", Value: 1}, Eq[int]{Ident: "b", Value: 2}}},
+		Or{Exprs: []Expr{Eq[int]{Ident: "c", Value: 3}, Eq[int]{Ident: "d", Value: 4}}},
+	}}
+	m, err := e.Map(DocDB)
+	req.NoError(err)
+	mm, ok := m.(Map)
+	req.True(ok)
+	req.JSONEq(`{"$and":[{"$or":[{"a":1},{"b":2}]},{"$or":[{"c":3},{"d":4}]}]}`, string(mm.JSON()))
+}
+
+func TestOpensearchFilter(t *testing.T) {
+	req := require.New(t)
+
+	e := Filter{Expr: Eq[int]{Ident: "a", Value: 1234}}
+	m, err := e.Map(OpenSearch)
+	req.NoError(err)
+	req.Equal(Map{[]KVPair{
+		{"bool", Map{[]KVPair{
+			{"filter", []Map{
+				{[]KVPair{{"term", Map{[]KVPair{{"a", 1234}}}}}},
+			}},
+		}}},
+	}}, m)
+
+	m, err = e.Map(DocDB)
+	req.NoError(err)
+	req.Equal(Map{[]KVPair{{"a", 1234}}}, m)
+}
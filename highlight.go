@@ -0,0 +1,77 @@
+package search
+
+import "encoding/json"
+
+// Highlighter types.
+const (
+	HighlighterUnified = "unified"
+	HighlighterPlain   = "plain"
+	HighlighterFVH     = "fvh"
+)
+
+// Highlight specifies highlighting of search hits.
+// The highlighted fragments are returned in [IDedDocument.Highlight].
+type Highlight struct {
+	Fields []string
+	// FragmentSize is the size of fragments in characters.
+	FragmentSize int
+	// NumberOfFragments is the maximum number of fragments; zero returns the whole field.
+	NumberOfFragments *int
+	PreTags           []string
+	PostTags          []string
+	// Type is the highlighter type, see [HighlighterUnified], [HighlighterPlain] and [HighlighterFVH].
+	Type string
+	// Query highlights the matches of the expression instead of the search query.
+	Query Expr
+}
+
+// Map returns the query map corresponding to the highlight.
+func (h Highlight) Map() (Map, error) {
+	fields := Map{Pairs: make([]KVPair, 0, len(h.Fields))}
+	for _, f := range h.Fields {
+		fields.Pairs = append(fields.Pairs, KVPair{f, Map{}})
+	}
+
+	m := Map{Pairs: []KVPair{{"fields", fields}}}
+	if h.FragmentSize > 0 {
+		m.Pairs = append(m.Pairs, KVPair{"fragment_size", h.FragmentSize})
+	}
+	if h.NumberOfFragments != nil {
+		m.Pairs = append(m.Pairs, KVPair{"number_of_fragments", *h.NumberOfFragments})
+	}
+	if len(h.PreTags) > 0 {
+		m.Pairs = append(m.Pairs, KVPair{"pre_tags", h.PreTags})
+	}
+	if len(h.PostTags) > 0 {
+		m.Pairs = append(m.Pairs, KVPair{"post_tags", h.PostTags})
+	}
+	if h.Type != "" {
+		m.Pairs = append(m.Pairs, KVPair{"type", h.Type})
+	}
+	if h.Query != nil {
+		em, err := osClause(h.Query)
+		if err != nil {
+			return Map{}, err
+		}
+		m.Pairs = append(m.Pairs, KVPair{"highlight_query", em})
+	}
+	return m, nil
+}
+
+// MarshalJSON marshals the highlight into JSON.
+func (h Highlight) MarshalJSON() ([]byte, error) {
+	m, err := h.Map()
+	if err != nil {
+		return nil, err
+	}
+	return m.JSON(), nil
+}
+
+var _ json.Marshaler = Highlight{}
+
+// WithHighlight highlights the matches in the hits.
+func WithHighlight(h Highlight) SearchOption {
+	return func(q *searchQuery) {
+		q.Highlight = &h
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"net/http"
+	"time"
 
 	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
 
@@ -56,3 +58,283 @@ func IndexDelete(ctx context.Context, client *opensearchapi.Client, index string
 
 	return nil
 }
+
+// IndexExists checks if the index exists.
+func IndexExists(ctx context.Context, client *opensearchapi.Client, index string) (bool, error) {
+	resp, err := client.Indices.Exists(ctx, opensearchapi.IndicesExistsReq{
+		Indices: []string{index},
+	})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, serr.Wrap("checking index existence", err, serr.String("index", index))
+	}
+
+	return true, nil
+}
+
+// IndexGetMappings returns the mappings of the indices matching the name, which can be an alias or a pattern.
+// The mappings are keyed by the index names.
+func IndexGetMappings(ctx context.Context, client *opensearchapi.Client, index string) (map[string]json.RawMessage, error) {
+	resp, err := client.Indices.Mapping.Get(ctx, &opensearchapi.MappingGetReq{
+		Indices: []string{index},
+	})
+	if err != nil {
+		return nil, serr.Wrap("getting mapping", err, serr.String("index", index))
+	}
+
+	mappings := make(map[string]json.RawMessage, len(resp.Indices))
+	for name, idx := range resp.Indices {
+		mappings[name] = idx.Mappings
+	}
+	return mappings, nil
+}
+
+// IndexGetMapping returns the mapping of the index, or of the single index behind the alias.
+func IndexGetMapping(ctx context.Context, client *opensearchapi.Client, index string) (json.RawMessage, error) {
+	mappings, err := IndexGetMappings(ctx, client, index)
+	if err != nil {
+		return nil, err
+	}
+	if len(mappings) != 1 {
+		return nil, serr.New("unexpected index count", serr.String("index", index), serr.Int("count", len(mappings)))
+	}
+	for _, m := range mappings {
+		return m, nil
+	}
+	return nil, nil
+}
+
+// IndexPutMapping adds fields to the mapping of the index.
+// Existing fields can't be changed except for a few parameters, see [DiffMappings].
+func IndexPutMapping(ctx context.Context, client *opensearchapi.Client, index string, mapping json.RawMessage) error {
+	resp, err := client.Indices.Mapping.Put(ctx, opensearchapi.MappingPutReq{
+		Indices: []string{index},
+		Body:    bytes.NewReader(mapping),
+	})
+	if err != nil {
+		return serr.Wrap("putting mapping", err, serr.String("index", index))
+	}
+	if !resp.Acknowledged {
+		return serr.New("mapping update not acknowledged", serr.String("index", index))
+	}
+
+	return nil
+}
+
+// IndexGetSettings returns the settings of the index, or of the single index behind the alias.
+func IndexGetSettings(ctx context.Context, client *opensearchapi.Client, index string) (json.RawMessage, error) {
+	resp, err := client.Indices.Settings.Get(ctx, &opensearchapi.SettingsGetReq{
+		Indices: []string{index},
+	})
+	if err != nil {
+		return nil, serr.Wrap("getting settings", err, serr.String("index", index))
+	}
+	if len(resp.Indices) != 1 {
+		return nil, serr.New("unexpected index count", serr.String("index", index), serr.Int("count", len(resp.Indices)))
+	}
+	for _, idx := range resp.Indices {
+		return idx.Settings, nil
+	}
+	return nil, nil
+}
+
+// IndexUpdateSettings updates the dynamic settings of the index, e.g. {"index":{"number_of_replicas":0,"refresh_interval":"-1"}}.
+func IndexUpdateSettings(ctx context.Context, client *opensearchapi.Client, index string, settings json.RawMessage) error {
+	resp, err := client.Indices.Settings.Put(ctx, opensearchapi.SettingsPutReq{
+		Indices: []string{index},
+		Body:    bytes.NewReader(settings),
+	})
+	if err != nil {
+		return serr.Wrap("updating settings", err, serr.String("index", index))
+	}
+	if !resp.Acknowledged {
+		return serr.New("settings update not acknowledged", serr.String("index", index))
+	}
+
+	return nil
+}
+
+// IndexRefresh makes the recent changes of the index visible to search.
+func IndexRefresh(ctx context.Context, client *opensearchapi.Client, index string) error {
+	resp, err := client.Indices.Refresh(ctx, &opensearchapi.IndicesRefreshReq{
+		Indices: []string{index},
+	})
+	if err != nil {
+		return serr.Wrap("refreshing index", err, serr.String("index", index))
+	}
+	if resp.Shards.Failed > 0 {
+		return serr.New("index refresh failed on shards", serr.String("index", index), serr.Int("failedShards", resp.Shards.Failed))
+	}
+
+	return nil
+}
+
+// IndexFlush persists the data of the index held in the transaction log.
+func IndexFlush(ctx context.Context, client *opensearchapi.Client, index string) error {
+	resp, err := client.Indices.Flush(ctx, &opensearchapi.IndicesFlushReq{
+		Indices: []string{index},
+	})
+	if err != nil {
+		return serr.Wrap("flushing index", err, serr.String("index", index))
+	}
+	if resp.Shards.Failed > 0 {
+		return serr.New("index flush failed on shards", serr.String("index", index), serr.Int("failedShards", resp.Shards.Failed))
+	}
+
+	return nil
+}
+
+// IndexForceMerge merges the segments of the index down to maxNumSegments; zero lets OpenSearch decide.
+// It's meant for indices which are no longer written to.
+func IndexForceMerge(ctx context.Context, client *opensearchapi.Client, index string, maxNumSegments int) error {
+	req := opensearchapi.IndicesForcemergeReq{
+		Indices: []string{index},
+	}
+	if maxNumSegments > 0 {
+		req.Params.MaxNumSegments = &maxNumSegments
+	}
+	resp, err := client.Indices.Forcemerge(ctx, &req)
+	if err != nil {
+		return serr.Wrap("force merging index", err, serr.String("index", index))
+	}
+	if resp.Shards.Failed > 0 {
+		return serr.New("index force merge failed on shards", serr.String("index", index), serr.Int("failedShards", resp.Shards.Failed))
+	}
+
+	return nil
+}
+
+// IndexOpen opens a closed index.
+func IndexOpen(ctx context.Context, client *opensearchapi.Client, index string) error {
+	resp, err := client.Indices.Open(ctx, opensearchapi.IndicesOpenReq{
+		Index: index,
+	})
+	if err != nil {
+		return serr.Wrap("opening index", err, serr.String("index", index))
+	}
+	if !resp.Acknowledged {
+		return serr.New("index open not acknowledged", serr.String("index", index))
+	}
+
+	return nil
+}
+
+// IndexClose closes the index. A closed index can't be read or written but keeps its data.
+func IndexClose(ctx context.Context, client *opensearchapi.Client, index string) error {
+	resp, err := client.Indices.Close(ctx, opensearchapi.IndicesCloseReq{
+		Index: index,
+	})
+	if err != nil {
+		return serr.Wrap("closing index", err, serr.String("index", index))
+	}
+	if !resp.Acknowledged {
+		return serr.New("index close not acknowledged", serr.String("index", index))
+	}
+
+	return nil
+}
+
+type indexResizeBody struct {
+	Settings json.RawMessage `json:"settings,omitempty"`
+}
+
+// IndexClone clones the index into the target index with the optional settings.
+// The index must be read-only, i.e. have the index.blocks.write setting.
+func IndexClone(ctx context.Context, client *opensearchapi.Client, index, target string, settings json.RawMessage) error {
+	b, err := json.Marshal(indexResizeBody{Settings: settings})
+	if err != nil {
+		return serr.Wrap("marshalling index clone body", err)
+	}
+
+	resp, err := client.Indices.Clone(ctx, opensearchapi.IndicesCloneReq{
+		Index:  index,
+		Target: target,
+		Body:   bytes.NewReader(b),
+	})
+	if err != nil {
+		return serr.Wrap("cloning index", err, serr.String("index", index), serr.String("target", target))
+	}
+	if !resp.Acknowledged {
+		return serr.New("index clone not acknowledged", serr.String("index", index), serr.String("target", target))
+	}
+
+	return nil
+}
+
+// IndexShrink shrinks the index into the target index with fewer primary shards given by the settings.
+// The index must be read-only and a copy of each of its shards must reside on the same node.
+func IndexShrink(ctx context.Context, client *opensearchapi.Client, index, target string, settings json.RawMessage) error {
+	b, err := json.Marshal(indexResizeBody{Settings: settings})
+	if err != nil {
+		return serr.Wrap("marshalling index shrink body", err)
+	}
+
+	resp, err := client.Indices.Shrink(ctx, opensearchapi.IndicesShrinkReq{
+		Index:  index,
+		Target: target,
+		Body:   bytes.NewReader(b),
+	})
+	if err != nil {
+		return serr.Wrap("shrinking index", err, serr.String("index", index), serr.String("target", target))
+	}
+	if !resp.Acknowledged {
+		return serr.New("index shrink not acknowledged", serr.String("index", index), serr.String("target", target))
+	}
+
+	return nil
+}
+
+// IndexSplit splits the index into the target index with more primary shards given by the settings.
+// The index must be read-only.
+func IndexSplit(ctx context.Context, client *opensearchapi.Client, index, target string, settings json.RawMessage) error {
+	b, err := json.Marshal(indexResizeBody{Settings: settings})
+	if err != nil {
+		return serr.Wrap("marshalling index split body", err)
+	}
+
+	resp, err := client.Indices.Split(ctx, opensearchapi.IndicesSplitReq{
+		Index:  index,
+		Target: target,
+		Body:   bytes.NewReader(b),
+	})
+	if err != nil {
+		return serr.Wrap("splitting index", err, serr.String("index", index), serr.String("target", target))
+	}
+	if !resp.Acknowledged {
+		return serr.New("index split not acknowledged", serr.String("index", index), serr.String("target", target))
+	}
+
+	return nil
+}
+
+// HealthStatus is the health status of a cluster or an index.
+type HealthStatus string
+
+// Health statuses.
+const (
+	HealthGreen  HealthStatus = "green"
+	HealthYellow HealthStatus = "yellow"
+	HealthRed    HealthStatus = "red"
+)
+
+// IndexWaitForHealth waits until the index reaches at least the health status or the timeout expires.
+// Zero timeout means the OpenSearch default of 30s.
+func IndexWaitForHealth(ctx context.Context, client *opensearchapi.Client, index string, status HealthStatus, timeout time.Duration) error {
+	resp, err := client.Cluster.Health(ctx, &opensearchapi.ClusterHealthReq{
+		Indices: []string{index},
+		Params: opensearchapi.ClusterHealthParams{
+			WaitForStatus: string(status),
+			Timeout:       timeout,
+		},
+	})
+	if err != nil {
+		return serr.Wrap("waiting for index health", err, serr.String("index", index), serr.String("status", string(status)))
+	}
+	if resp.TimedOut {
+		return serr.New("index health timed out", serr.String("index", index), serr.String("status", string(status)), serr.String("actualStatus", resp.Status))
+	}
+
+	return nil
+}
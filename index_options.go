@@ -11,6 +11,15 @@ func WithIndexParamVersion(v int) IndexParam {
 	}
 }
 
+// WithIndexParamIfSeqNo indexes the document only if it has the given sequence number and primary term.
+// Otherwise, [ErrDocumentHasNewerVersion] is returned.
+func WithIndexParamIfSeqNo(seqNo, primaryTerm int) IndexParam {
+	return func(params *opensearchapi.IndexParams) {
+		params.IfSeqNo = &seqNo
+		params.IfPrimaryTerm = &primaryTerm
+	}
+}
+
 // WithIndexParamVersionType allows to set refresh type of index params.
 func WithIndexParamRefresh(refreshType RefreshType) IndexParam {
 	return func(params *opensearchapi.IndexParams) {
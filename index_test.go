@@ -0,0 +1,73 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexAdmin(t *testing.T) {
+	req := require.New(t)
+
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := r.Method + " " + r.URL.Path
+		if q := r.URL.RawQuery; q != "" {
+			call += "?" + q
+		}
+		calls = append(calls, call)
+		w.Header().Set("Content-Type", "application/json")
+		switch call {
+		case "HEAD /orders-v6":
+		case "HEAD /orders-v7":
+			w.WriteHeader(http.StatusNotFound)
+		case "GET /orders/_settings":
+			_, _ = io.WriteString(w, `{"orders-v6":{"settings":{"index":{"number_of_replicas":"1"}}}}`)
+		case "GET /orders/_mapping":
+			_, _ = io.WriteString(w, `{"orders-v6":{"mappings":{"properties":{}}}}`)
+		case "PUT /orders-v6/_settings", "POST /orders-v6/_close", "POST /orders-v6/_open":
+			_, _ = io.WriteString(w, `{"acknowledged":true}`)
+		case "POST /orders-v6/_forcemerge?max_num_segments=1":
+			_, _ = io.WriteString(w, `{"_shards":{"total":2,"successful":1,"failed":1}}`)
+		case "PUT /orders-v6/_split/orders-v6-split":
+			_, _ = io.WriteString(w, `{"acknowledged":false}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":"unexpected call"}`)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := opensearchapi.NewClient(opensearchapi.Config{Client: opensearch.Config{Addresses: []string{srv.URL}}})
+	req.NoError(err)
+	ctx := context.Background()
+
+	exists, err := IndexExists(ctx, client, "orders-v6")
+	req.NoError(err)
+	req.True(exists)
+	exists, err = IndexExists(ctx, client, "orders-v7")
+	req.NoError(err)
+	req.False(exists)
+
+	settings, err := IndexGetSettings(ctx, client, "orders")
+	req.NoError(err)
+	req.JSONEq(`{"index":{"number_of_replicas":"1"}}`, string(settings))
+
+	mapping, err := IndexGetMapping(ctx, client, "orders")
+	req.NoError(err)
+	req.JSONEq(`{"properties":{}}`, string(mapping))
+
+	req.NoError(IndexUpdateSettings(ctx, client, "orders-v6", json.RawMessage(`{"index":{"refresh_interval":"-1"}}`)))
+	req.NoError(IndexClose(ctx, client, "orders-v6"))
+	req.NoError(IndexOpen(ctx, client, "orders-v6"))
+	req.Error(IndexForceMerge(ctx, client, "orders-v6", 1))
+	req.Error(IndexSplit(ctx, client, "orders-v6", "orders-v6-split", json.RawMessage(`{"index.number_of_shards":4}`)))
+	req.Error(IndexRefresh(ctx, client, "orders-v6"))
+}
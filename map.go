@@ -68,10 +68,16 @@ func appendValue(b []byte, x any) []byte {
 		return appendSlice(b, x)
 	case []string:
 		return appendSlice(b, x)
+	case []float64:
+		return appendSlice(b, x)
 	case []any:
 		return appendSlice(b, x)
+	case []Map:
+		return appendSlice(b, x)
 	case Map:
 		return x.appendJSON(b)
+	case json.RawMessage:
+		return append(b, x...)
 	}
 	panic(fmt.Sprintf("unknown value type: %v (%T)", x, x))
 }
@@ -0,0 +1,232 @@
+package search
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mailstepcz/serr"
+)
+
+var (
+	// ErrMappingUnsupportedType signifies that the mapping type of a field can't be inferred.
+	// The type can be given explicitly by the search tag.
+	ErrMappingUnsupportedType = errors.New("unsupported mapping type")
+	// ErrMappingInvalidTag signifies a malformed search tag.
+	ErrMappingInvalidTag = errors.New("invalid search tag")
+)
+
+// Mapping types with special handling.
+const (
+	MappingTypeNested = "nested"
+	MappingTypeObject = "object"
+)
+
+// keywordIgnoreAbove is the length of strings above which the keyword subfield isn't indexed.
+const keywordIgnoreAbove = 256
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// MappingOf generates the index mapping of the documents of type T, as accepted by [IndexCreate].
+//
+// Field names are taken from the json tags. Mapping types are inferred from Go types: strings and text marshalers
+// such as uuid.UUID are keywords, [time.Time] is a date, numerics map to the numeric types of the same size,
+// slices map to their element types and structs, including embedded ones, map to objects.
+// The inference can be adjusted by the search tag with comma-separated options:
+//
+//   - type=T sets the mapping type, e.g. type=text
+//   - analyzer=A sets the analyzer of a text field
+//   - format=F sets the format of a date field
+//   - keyword adds a keyword subfield, e.g. for sorting by a text field
+//   - index=false disables indexing of the field
+//   - nested or object maps a struct as a nested or object field
+//   - - omits the field from the mapping
+func MappingOf[T any]() (json.RawMessage, error) {
+	props, err := mappingProperties(reflect.TypeFor[T](), "", map[reflect.Type]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return Map{Pairs: []KVPair{{"properties", props}}}.JSON(), nil
+}
+
+func mappingProperties(t reflect.Type, path string, seen map[reflect.Type]bool) (Map, error) {
+	t = indirect(t)
+	if seen[t] {
+		return Map{}, serr.Wrap("recursive type", ErrMappingUnsupportedType, serr.String("field", path), serr.String("type", t.String()))
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	var props Map
+	for _, f := range jsonFields(t) {
+		fieldPath := path + f.name
+		tag, err := parseSearchTag(f.Tag.Get("search"))
+		if err != nil {
+			return Map{}, serr.Wrap("parsing search tag", err, serr.String("field", fieldPath))
+		}
+		if tag.omit {
+			continue
+		}
+		m, err := fieldMapping(f.Type, tag, fieldPath, seen)
+		if err != nil {
+			return Map{}, err
+		}
+		props.Pairs = append(props.Pairs, KVPair{f.name, m})
+	}
+	return props, nil
+}
+
+func fieldMapping(t reflect.Type, tag searchTag, path string, seen map[reflect.Type]bool) (Map, error) {
+	t = mappingElem(t)
+
+	typ := tag.typ
+	if typ == "" {
+		var err error
+		if typ, err = inferMappingType(t); err != nil {
+			return Map{}, serr.Wrap("inferring mapping type", err, serr.String("field", path), serr.String("type", t.String()))
+		}
+	}
+
+	var m Map
+	switch {
+	case (typ == MappingTypeNested || typ == MappingTypeObject) && t.Kind() == reflect.Struct:
+		props, err := mappingProperties(t, path+".", seen)
+		if err != nil {
+			return Map{}, err
+		}
+		if typ == MappingTypeNested {
+			m.Pairs = append(m.Pairs, KVPair{"type", typ})
+		}
+		m.Pairs = append(m.Pairs, KVPair{"properties", props})
+	case (typ == MappingTypeNested || typ == MappingTypeObject) && t.Kind() != reflect.Map:
+		return Map{}, serr.Wrap("expected struct", ErrMappingInvalidTag, serr.String("field", path), serr.String("type", t.String()))
+	default:
+		m.Pairs = append(m.Pairs, KVPair{"type", typ})
+	}
+
+	if tag.analyzer != "" {
+		m.Pairs = append(m.Pairs, KVPair{"analyzer", tag.analyzer})
+	}
+	if tag.format != "" {
+		m.Pairs = append(m.Pairs, KVPair{"format", tag.format})
+	}
+	if tag.noIndex {
+		m.Pairs = append(m.Pairs, KVPair{"index", false})
+	}
+	if tag.keyword {
+		m.Pairs = append(m.Pairs, KVPair{"fields", Map{Pairs: []KVPair{
+			{"keyword", Map{Pairs: []KVPair{
+				{"type", "keyword"},
+				{"ignore_above", keywordIgnoreAbove},
+			}}},
+		}}})
+	}
+	return m, nil
+}
+
+// mappingElem returns the type whose mapping applies to the field, i.e. the element type of slices.
+func mappingElem(t reflect.Type) reflect.Type {
+	for {
+		switch {
+		case t.Kind() == reflect.Pointer:
+			t = t.Elem()
+		case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+func inferMappingType(t reflect.Type) (string, error) {
+	switch {
+	case t == timeType:
+		return "date", nil
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return "", ErrMappingUnsupportedType
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return "keyword", nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "keyword", nil
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int8:
+		return "byte", nil
+	case reflect.Int16, reflect.Uint8:
+		return "short", nil
+	case reflect.Int32, reflect.Uint16:
+		return "integer", nil
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "long", nil
+	case reflect.Uint, reflect.Uint64:
+		return "unsigned_long", nil
+	case reflect.Float32:
+		return "float", nil
+	case reflect.Float64:
+		return "double", nil
+	case reflect.Slice, reflect.Array:
+		return "binary", nil // byte slices are encoded in base64
+	case reflect.Struct, reflect.Map:
+		return MappingTypeObject, nil
+	}
+	return "", ErrMappingUnsupportedType
+}
+
+type searchTag struct {
+	omit     bool
+	typ      string
+	analyzer string
+	format   string
+	keyword  bool
+	noIndex  bool
+}
+
+func parseSearchTag(s string) (searchTag, error) {
+	var tag searchTag
+	if s == "" {
+		return tag, nil
+	}
+	if s == "-" {
+		tag.omit = true
+		return tag, nil
+	}
+
+	for opt := range strings.SplitSeq(s, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		switch key {
+		case "type":
+			tag.typ = value
+		case "analyzer":
+			tag.analyzer = value
+		case "format":
+			tag.format = value
+		case "keyword":
+			tag.keyword = true
+		case "index":
+			index, err := strconv.ParseBool(value)
+			if err != nil {
+				return tag, serr.Wrap("", ErrMappingInvalidTag, serr.String("option", opt))
+			}
+			tag.noIndex = !index
+		case MappingTypeNested, MappingTypeObject:
+			tag.typ = key
+		default:
+			return tag, serr.Wrap("unknown option", ErrMappingInvalidTag, serr.String("option", opt))
+		}
+		if value == "" && (key == "type" || key == "analyzer" || key == "format") {
+			return tag, serr.Wrap("missing value", ErrMappingInvalidTag, serr.String("option", opt))
+		}
+	}
+	return tag, nil
+}
@@ -0,0 +1,94 @@
+package search
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type mappingBase struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"createdAt" search:"format=strict_date_time"`
+}
+
+type mappingLine struct {
+	SKU      string  `json:"sku"`
+	Quantity int32   `json:"quantity"`
+	Price    float64 `json:"price"`
+}
+
+type mappingDoc struct {
+	mappingBase
+	Number   string        `json:"number"`
+	Note     string        `json:"note" search:"type=text,analyzer=czech,keyword"`
+	Payload  []byte        `json:"payload" search:"index=false"`
+	Tags     []string      `json:"tags"`
+	Lines    []mappingLine `json:"lines" search:"nested"`
+	Customer *struct {
+		Name string `json:"name"`
+	} `json:"customer"`
+	Active   bool              `json:"active"`
+	Attrs    map[string]string `json:"attrs"`
+	Internal string            `json:"internal" search:"-"`
+	Ignored  string            `json:"-"`
+}
+
+func TestMappingOf(t *testing.T) {
+	req := require.New(t)
+
+	m, err := MappingOf[mappingDoc]()
+	req.NoError(err)
+	req.JSONEq(`{"properties":{
+		"id":{"type":"keyword"},
+		"createdAt":{"type":"date","format":"strict_date_time"},
+		"number":{"type":"keyword"},
+		"note":{"type":"text","analyzer":"czech","fields":{"keyword":{"type":"keyword","ignore_above":256}}},
+		"payload":{"type":"binary","index":false},
+		"tags":{"type":"keyword"},
+		"lines":{"type":"nested","properties":{
+			"sku":{"type":"keyword"},
+			"quantity":{"type":"integer"},
+			"price":{"type":"double"}
+		}},
+		"customer":{"properties":{"name":{"type":"keyword"}}},
+		"active":{"type":"boolean"},
+		"attrs":{"type":"object"}
+	}}`, string(m))
+}
+
+type mappingTree struct {
+	Children []mappingTree `json:"children"`
+}
+
+type mappingRaw struct {
+	Data json.RawMessage `json:"data"`
+}
+
+func TestMappingOfErrors(t *testing.T) {
+	req := require.New(t)
+
+	_, err := MappingOf[mappingTree]()
+	req.ErrorIs(err, ErrMappingUnsupportedType)
+
+	_, err = MappingOf[mappingRaw]()
+	req.ErrorIs(err, ErrMappingUnsupportedType)
+
+	_, err = MappingOf[struct {
+		A string `json:"a" search:"nested"`
+	}]()
+	req.ErrorIs(err, ErrMappingInvalidTag)
+
+	_, err = MappingOf[struct {
+		A string `json:"a" search:"analyser=czech"`
+	}]()
+	req.ErrorIs(err, ErrMappingInvalidTag)
+
+	m, err := MappingOf[struct {
+		Data json.RawMessage `json:"data" search:"type=flat_object"`
+	}]()
+	req.NoError(err)
+	req.JSONEq(`{"properties":{"data":{"type":"flat_object"}}}`, string(m))
+}
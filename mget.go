@@ -0,0 +1,150 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+
+	"github.com/mailstepcz/serr"
+)
+
+// DocRef references a document by its index and ID.
+type DocRef struct {
+	Index string
+	ID    string
+}
+
+// MGetItemResult holds the outcome of one document which couldn't be fetched.
+// Error wraps [ErrDocumentNotFound] for missing documents and indices, or [ErrOpensearchRequestFailed].
+type MGetItemResult struct {
+	Index string
+	ID    string
+	Error error
+}
+
+// MGetResult holds the documents found by a multi-get, in request order, and the ones which weren't.
+type MGetResult[T any] struct {
+	Docs   []IDedDocument[T]
+	Failed []MGetItemResult
+}
+
+// Err returns all per-item errors joined.
+func (r *MGetResult[T]) Err() error {
+	var errs error
+	for _, it := range r.Failed {
+		errs = errors.Join(errs, it.Error)
+	}
+	return errs
+}
+
+// MGet gets documents of an index in one round trip.
+// The options are the same as for [Get], e.g. [WithGetSourceOf].
+func MGet[T any](ctx context.Context, cl *opensearch.Client, index string, ids []string, opts ...GetOption) (*MGetResult[T], error) {
+	refs := make([]DocRef, 0, len(ids))
+	for _, id := range ids {
+		refs = append(refs, DocRef{Index: index, ID: id})
+	}
+	return MGetRefs[T](ctx, cl, refs, opts...)
+}
+
+// MGetRefs gets documents possibly spanning several indices in one round trip.
+func MGetRefs[T any](ctx context.Context, cl *opensearch.Client, refs []DocRef, opts ...GetOption) (*MGetResult[T], error) {
+	if len(refs) == 0 {
+		return &MGetResult[T]{}, nil
+	}
+
+	var params opensearchapi.DocumentGetParams
+	for _, opt := range opts {
+		opt(&params)
+	}
+
+	body := mgetBody{Docs: make([]mgetBodyDoc, 0, len(refs))}
+	for _, r := range refs {
+		body.Docs = append(body.Docs, mgetBodyDoc{Index: r.Index, ID: r.ID})
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req := opensearchapi.MGetReq{
+		Body: bytes.NewReader(b),
+		Params: opensearchapi.MGetParams{
+			Preference:     params.Preference,
+			Realtime:       params.Realtime,
+			Refresh:        params.Refresh,
+			Routing:        params.Routing,
+			Source:         params.Source,
+			SourceExcludes: params.SourceExcludes,
+			SourceIncludes: params.SourceIncludes,
+			StoredFields:   params.StoredFields,
+		},
+	}
+	var osResp mgetResp
+	resp, err := cl.Do(ctx, req, &osResp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, osError(resp)
+	}
+
+	var res MGetResult[T]
+	hits := make([]searchHit, 0, len(osResp.Docs))
+	for _, d := range osResp.Docs {
+		switch {
+		case d.Error != nil:
+			sentinel := ErrOpensearchRequestFailed
+			if d.Error.Type == "index_not_found_exception" {
+				sentinel = ErrDocumentNotFound
+			}
+			res.Failed = append(res.Failed, MGetItemResult{
+				Index: d.Index,
+				ID:    d.ID,
+				Error: serr.Wrap("getting document", sentinel,
+					serr.String("index", d.Index),
+					serr.String("id", d.ID),
+					serr.String("reason", d.Error.Reason)),
+			})
+		case !d.Found:
+			res.Failed = append(res.Failed, MGetItemResult{
+				Index: d.Index,
+				ID:    d.ID,
+				Error: serr.Wrap("", ErrDocumentNotFound, serr.String("index", d.Index), serr.String("id", d.ID)),
+			})
+		default:
+			hits = append(hits, d.searchHit)
+		}
+	}
+	if res.Docs, err = mapDocs[T](hits); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+type mgetBody struct {
+	Docs []mgetBodyDoc `json:"docs"`
+}
+
+type mgetBodyDoc struct {
+	Index string `json:"_index,omitempty"`
+	ID    string `json:"_id"`
+}
+
+type mgetResp struct {
+	Docs []mgetDoc `json:"docs"`
+}
+
+type mgetDoc struct {
+	searchHit
+	Found bool `json:"found"`
+	Error *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
@@ -0,0 +1,75 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMGet(t *testing.T) {
+	req := require.New(t)
+
+	var path, includes string
+	var body mgetBody
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		includes = r.URL.Query().Get("_source_includes")
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"docs":[
+			{"_index":"orders","_id":"3","_version":2,"_seq_no":5,"_primary_term":1,"found":true,"_source":{"name":"c"}},
+			{"_index":"orders","_id":"1","found":false},
+			{"_index":"orders-old","_id":"2","error":{"type":"index_not_found_exception","reason":"no such index [orders-old]"}},
+			{"_index":"orders","_id":"4","_version":1,"found":true,"_source":{"name":"d"}}
+		]}`)
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	type doc struct {
+		Name string `json:"name"`
+	}
+	res, err := MGetRefs[doc](context.Background(), cl, []DocRef{
+		{Index: "orders", ID: "3"},
+		{Index: "orders", ID: "1"},
+		{Index: "orders-old", ID: "2"},
+		{Index: "orders", ID: "4"},
+	}, WithGetSourceOf[doc]())
+	req.NoError(err)
+
+	req.Equal("/_mget", path)
+	req.Equal("name", includes)
+	req.Equal([]mgetBodyDoc{{"orders", "3"}, {"orders", "1"}, {"orders-old", "2"}, {"orders", "4"}}, body.Docs)
+
+	req.Len(res.Docs, 2)
+	req.Equal("3", res.Docs[0].ID)
+	req.Equal("c", res.Docs[0].Document.Name)
+	req.Equal(5, res.Docs[0].SeqNo)
+	req.Equal("4", res.Docs[1].ID)
+	req.Equal("d", res.Docs[1].Document.Name)
+
+	req.Len(res.Failed, 2)
+	req.Equal("1", res.Failed[0].ID)
+	req.ErrorIs(res.Failed[0].Error, ErrDocumentNotFound)
+	req.Equal("orders-old", res.Failed[1].Index)
+	req.ErrorIs(res.Failed[1].Error, ErrDocumentNotFound)
+	req.ErrorIs(res.Err(), ErrDocumentNotFound)
+}
+
+func TestMGetEmpty(t *testing.T) {
+	req := require.New(t)
+
+	res, err := MGet[struct{}](context.Background(), nil, "orders", nil)
+	req.NoError(err)
+	req.Empty(res.Docs)
+	req.NoError(res.Err())
+}
@@ -0,0 +1,267 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+
+	"github.com/mailstepcz/serr"
+)
+
+var (
+	// ErrMigrationNeedsAlias signifies that a breaking mapping change was planned for an index which isn't behind an alias,
+	// so it can't be reindexed without downtime.
+	ErrMigrationNeedsAlias = errors.New("breaking mapping change requires an alias")
+)
+
+// MappingChangeKind classifies a mapping change.
+type MappingChangeKind string
+
+// Mapping change kinds.
+const (
+	// MappingChangeCompatible is a change which can be applied by put-mapping, e.g. a new field.
+	MappingChangeCompatible MappingChangeKind = "compatible"
+	// MappingChangeBreaking is a change which requires a reindex, e.g. a type change.
+	MappingChangeBreaking MappingChangeKind = "breaking"
+)
+
+// MappingChange is a difference between two mappings.
+// Field is the dotted path of the field; subfields are separated by a dot as well.
+type MappingChange struct {
+	Field  string
+	Kind   MappingChangeKind
+	Reason string
+}
+
+// mappingDefaults are the values of parameters omitted in mappings.
+var mappingDefaults = map[string]any{
+	"analyzer":   "standard",
+	"index":      true,
+	"doc_values": true,
+	"store":      false,
+}
+
+// mappingUpdatable are the parameters of existing fields which can be changed by put-mapping.
+var mappingUpdatable = map[string]bool{
+	"dynamic":               true,
+	"ignore_above":          true,
+	"ignore_malformed":      true,
+	"meta":                  true,
+	"search_analyzer":       true,
+	"search_quote_analyzer": true,
+}
+
+// DiffMappings compares the current mapping with the desired one, both as accepted by [IndexCreate].
+// The changes are sorted by field. New fields and subfields are compatible, whereas removed fields and changes of types,
+// analyzers and most other parameters are breaking. Only the field properties are compared.
+func DiffMappings(current, desired json.RawMessage) ([]MappingChange, error) {
+	var cur, des map[string]any
+	if err := json.Unmarshal(current, &cur); err != nil {
+		return nil, serr.Wrap("unmarshalling current mapping", err)
+	}
+	if err := json.Unmarshal(desired, &des); err != nil {
+		return nil, serr.Wrap("unmarshalling desired mapping", err)
+	}
+
+	var changes []MappingChange
+	diffMappingFields(mappingChildren(cur, "properties"), mappingChildren(des, "properties"), "", "field", &changes)
+	return changes, nil
+}
+
+func diffMappingFields(cur, des map[string]any, prefix, noun string, changes *[]MappingChange) {
+	names := slices.Collect(maps.Keys(cur))
+	for name := range des {
+		if _, ok := cur[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		path := prefix + name
+		c, inCur := cur[name].(map[string]any)
+		d, inDes := des[name].(map[string]any)
+		switch {
+		case !inCur:
+			*changes = append(*changes, MappingChange{Field: path, Kind: MappingChangeCompatible, Reason: "new " + noun})
+		case !inDes:
+			*changes = append(*changes, MappingChange{Field: path, Kind: MappingChangeBreaking, Reason: noun + " removed"})
+		default:
+			diffMappingField(c, d, path, changes)
+		}
+	}
+}
+
+func diffMappingField(cur, des map[string]any, path string, changes *[]MappingChange) {
+	if ct, dt := mappingFieldType(cur), mappingFieldType(des); ct != dt {
+		*changes = append(*changes, MappingChange{
+			Field:  path,
+			Kind:   MappingChangeBreaking,
+			Reason: fmt.Sprintf("type changed from %s to %s", ct, dt),
+		})
+		return
+	}
+
+	params := make(map[string]struct{}, len(cur)+len(des))
+	for k := range cur {
+		params[k] = struct{}{}
+	}
+	for k := range des {
+		params[k] = struct{}{}
+	}
+	for _, k := range slices.Sorted(maps.Keys(params)) {
+		if k == "type" || k == "properties" || k == "fields" {
+			continue
+		}
+		cv, dv := mappingParam(cur, k), mappingParam(des, k)
+		if fmt.Sprint(cv) == fmt.Sprint(dv) {
+			continue
+		}
+		kind := MappingChangeBreaking
+		if mappingUpdatable[k] {
+			kind = MappingChangeCompatible
+		}
+		*changes = append(*changes, MappingChange{
+			Field:  path,
+			Kind:   kind,
+			Reason: fmt.Sprintf("%s changed from %v to %v", k, cv, dv),
+		})
+	}
+
+	diffMappingFields(mappingChildren(cur, "properties"), mappingChildren(des, "properties"), path+".", "field", changes)
+	diffMappingFields(mappingChildren(cur, "fields"), mappingChildren(des, "fields"), path+".", "subfield", changes)
+}
+
+func mappingFieldType(m map[string]any) string {
+	if t, ok := m["type"].(string); ok {
+		return t
+	}
+	return MappingTypeObject
+}
+
+func mappingParam(m map[string]any, k string) any {
+	if v, ok := m[k]; ok {
+		return v
+	}
+	return mappingDefaults[k]
+}
+
+func mappingChildren(m map[string]any, k string) map[string]any {
+	children, _ := m[k].(map[string]any)
+	return children
+}
+
+// indexSettingsNotCopyable are the index settings assigned by OpenSearch to each index.
+var indexSettingsNotCopyable = []string{"uuid", "creation_date", "provided_name", "version"}
+
+// MigrationPlan is the plan of migrating an index, possibly behind an alias, to a desired mapping.
+type MigrationPlan struct {
+	// Alias is empty if the plan was made for an index.
+	Alias string
+	// Index is the current index.
+	Index   string
+	Mapping json.RawMessage
+	Changes []MappingChange
+}
+
+// PlanMigration compares the live mapping of the index or alias with the desired mapping, e.g. one generated by [MappingOf].
+func PlanMigration(ctx context.Context, client *opensearchapi.Client, name string, desired json.RawMessage) (*MigrationPlan, error) {
+	mappings, err := IndexGetMappings(ctx, client, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(mappings) != 1 {
+		return nil, serr.New("unexpected index count", serr.String("name", name), serr.Int("count", len(mappings)))
+	}
+
+	plan := MigrationPlan{Mapping: desired}
+	for index, current := range mappings {
+		plan.Index = index
+		if plan.Changes, err = DiffMappings(current, desired); err != nil {
+			return nil, serr.Wrap("comparing mappings", err, serr.String("index", index))
+		}
+	}
+	if plan.Index != name {
+		plan.Alias = name
+	}
+
+	return &plan, nil
+}
+
+// Breaking returns true if any of the changes is breaking.
+func (p *MigrationPlan) Breaking() bool {
+	return slices.ContainsFunc(p.Changes, func(c MappingChange) bool {
+		return c.Kind == MappingChangeBreaking
+	})
+}
+
+// Apply applies the plan. Compatible changes are applied by put-mapping. Breaking changes are applied by [Reindex]
+// with the configuration whose alias and mapping are taken from the plan. If the configuration has no index,
+// the version suffix of the current index is incremented, e.g. orders-v6 becomes orders-v7. If it has no settings,
+// the settings of the current index are used, e.g. its shards and analyzers.
+// The reindex result is nil unless a reindex took place.
+func (p *MigrationPlan) Apply(ctx context.Context, client *opensearchapi.Client, cfg ReindexConfig) (*ReindexResult, error) {
+	if len(p.Changes) == 0 {
+		return nil, nil
+	}
+	if !p.Breaking() {
+		return nil, IndexPutMapping(ctx, client, p.Index, p.Mapping)
+	}
+
+	if p.Alias == "" {
+		return nil, serr.Wrap("", ErrMigrationNeedsAlias, serr.String("index", p.Index))
+	}
+	cfg.Alias = p.Alias
+	cfg.Mapping = p.Mapping
+	if cfg.Index == "" {
+		cfg.Index = nextIndexVersion(p.Index)
+	}
+	if cfg.Settings == nil {
+		var err error
+		if cfg.Settings, err = copyableSettings(ctx, client, p.Index); err != nil {
+			return nil, err
+		}
+	}
+	return Reindex(ctx, client, cfg)
+}
+
+// copyableSettings returns the settings of the index without the ones assigned by OpenSearch,
+// so that they can be used to create another index.
+func copyableSettings(ctx context.Context, client *opensearchapi.Client, index string) (json.RawMessage, error) {
+	b, err := IndexGetSettings(ctx, client, index)
+	if err != nil {
+		return nil, err
+	}
+	var settings map[string]any
+	if err := json.Unmarshal(b, &settings); err != nil {
+		return nil, serr.Wrap("unmarshalling index settings", err, serr.String("index", index))
+	}
+	if idx, ok := settings["index"].(map[string]any); ok {
+		for _, k := range indexSettingsNotCopyable {
+			delete(idx, k)
+		}
+	}
+	if b, err = json.Marshal(settings); err != nil {
+		return nil, serr.Wrap("marshalling index settings", err, serr.String("index", index))
+	}
+	return b, nil
+}
+
+var indexVersionRe = regexp.MustCompile(`^(.*)-v(\d+)$`)
+
+// nextIndexVersion increments the version suffix of the index name, orders-v6 becomes orders-v7 and orders becomes orders-v2.
+func nextIndexVersion(index string) string {
+	m := indexVersionRe.FindStringSubmatch(index)
+	if m == nil {
+		return index + "-v2"
+	}
+	v, _ := strconv.Atoi(m[2])
+	return m[1] + "-v" + strconv.Itoa(v+1)
+}
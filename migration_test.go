@@ -0,0 +1,108 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffMappings(t *testing.T) {
+	req := require.New(t)
+
+	current := json.RawMessage(`{"properties":{
+		"number":{"type":"keyword","ignore_above":100},
+		"note":{"type":"text","analyzer":"czech"},
+		"legacy":{"type":"keyword"},
+		"createdAt":{"type":"date"},
+		"customer":{"properties":{"name":{"type":"keyword"}}},
+		"lines":{"type":"nested","properties":{"sku":{"type":"keyword"}}}
+	}}`)
+	desired := json.RawMessage(`{"properties":{
+		"number":{"type":"keyword","ignore_above":256,"index":true},
+		"note":{"type":"text","analyzer":"english","fields":{"keyword":{"type":"keyword"}}},
+		"createdAt":{"type":"date_nanos"},
+		"customer":{"properties":{"name":{"type":"keyword"},"email":{"type":"keyword"}}},
+		"lines":{"type":"nested","properties":{"sku":{"type":"keyword"},"quantity":{"type":"integer"}}},
+		"state":{"type":"keyword"}
+	}}`)
+
+	changes, err := DiffMappings(current, desired)
+	req.NoError(err)
+	req.Equal([]MappingChange{
+		{Field: "createdAt", Kind: MappingChangeBreaking, Reason: "type changed from date to date_nanos"},
+		{Field: "customer.email", Kind: MappingChangeCompatible, Reason: "new field"},
+		{Field: "legacy", Kind: MappingChangeBreaking, Reason: "field removed"},
+		{Field: "lines.quantity", Kind: MappingChangeCompatible, Reason: "new field"},
+		{Field: "note", Kind: MappingChangeBreaking, Reason: "analyzer changed from czech to english"},
+		{Field: "note.keyword", Kind: MappingChangeCompatible, Reason: "new subfield"},
+		{Field: "number", Kind: MappingChangeCompatible, Reason: "ignore_above changed from 100 to 256"},
+		{Field: "state", Kind: MappingChangeCompatible, Reason: "new field"},
+	}, changes)
+
+	changes, err = DiffMappings(current, current)
+	req.NoError(err)
+	req.Empty(changes)
+}
+
+func TestMigrationPlanPutMapping(t *testing.T) {
+	req := require.New(t)
+
+	c := &fakeCluster{aliasIndex: "orders-v6", mapping: `{"properties":{"number":{"type":"keyword"}}}`}
+	client := newFakeClusterClient(t, c)
+
+	plan, err := PlanMigration(context.Background(), client, "orders",
+		json.RawMessage(`{"properties":{"number":{"type":"keyword"},"state":{"type":"keyword"}}}`))
+	req.NoError(err)
+	req.Equal("orders", plan.Alias)
+	req.Equal("orders-v6", plan.Index)
+	req.False(plan.Breaking())
+
+	res, err := plan.Apply(context.Background(), client, ReindexConfig{})
+	req.NoError(err)
+	req.Nil(res)
+	req.Equal([]string{"GET /orders/_mapping", "PUT /orders-v6/_mapping"}, c.calls)
+}
+
+func TestMigrationPlanReindex(t *testing.T) {
+	req := require.New(t)
+
+	c := &fakeCluster{
+		aliasIndex: "orders-v6",
+		mapping:    `{"properties":{"number":{"type":"keyword"}}}`,
+		settings: `{"index":{"number_of_shards":"3","uuid":"x1","creation_date":"1790812800000","provided_name":"orders-v6",
+			"version":{"created":"136387927"},"analysis":{"analyzer":{"folding":{"tokenizer":"standard"}}}}}`,
+		counts: map[string]int{"orders-v6": 3, "orders-v7": 3},
+	}
+	client := newFakeClusterClient(t, c)
+
+	plan, err := PlanMigration(context.Background(), client, "orders",
+		json.RawMessage(`{"properties":{"number":{"type":"text"}}}`))
+	req.NoError(err)
+	req.True(plan.Breaking())
+
+	res, err := plan.Apply(context.Background(), client, ReindexConfig{PollInterval: time.Millisecond})
+	req.NoError(err)
+	req.Equal("orders-v7", res.Index)
+	req.Equal(3, res.Copied)
+	req.Contains(c.calls, "PUT /orders-v7")
+	req.Equal(map[string]any{"index": map[string]any{
+		"number_of_shards": "3",
+		"analysis":         map[string]any{"analyzer": map[string]any{"folding": map[string]any{"tokenizer": "standard"}}},
+	}}, c.createBody["settings"])
+	req.Contains(c.calls, "POST /_aliases")
+
+	plan.Alias = ""
+	_, err = plan.Apply(context.Background(), client, ReindexConfig{})
+	req.ErrorIs(err, ErrMigrationNeedsAlias)
+}
+
+func TestNextIndexVersion(t *testing.T) {
+	req := require.New(t)
+
+	req.Equal("orders-v7", nextIndexVersion("orders-v6"))
+	req.Equal("orders-v10", nextIndexVersion("orders-v9"))
+	req.Equal("orders-v2", nextIndexVersion("orders"))
+}
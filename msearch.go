@@ -0,0 +1,147 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+
+	"github.com/mailstepcz/serr"
+)
+
+// MSearchRequest is one of the searches sent together by [MSearch].
+// The fields have the same meaning as the arguments of [Search].
+type MSearchRequest struct {
+	Index   string
+	Expr    Expr
+	OrderBy string
+	Pag     *Pagination
+	Opts    []SearchOption
+}
+
+// MSearchResult is the result of one of the searches sent by [MSearch].
+// Err is set if the search failed; the documents are decoded by [MSearchResponse].
+type MSearchResult struct {
+	Err  error
+	resp *searchResp
+}
+
+// MSearch sends several searches in one round trip.
+// The results are in the order of the requests. A failing search doesn't fail the others, its error is in [MSearchResult.Err];
+// this includes searches which can't be built, e.g. ones exceeding [MaxResultWindow], which aren't sent at all.
+func MSearch(ctx context.Context, cl *opensearch.Client, reqs []MSearchRequest) ([]MSearchResult, error) {
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	// nolint:makezero
+	results := make([]MSearchResult, len(reqs))
+	var body bytes.Buffer
+	sent := make([]int, 0, len(reqs))
+	for i, r := range reqs {
+		b, err := msearchLines(r)
+		if err != nil {
+			results[i].Err = serr.Wrap("building search", err, serr.String("index", r.Index))
+			continue
+		}
+		body.Write(b)
+		sent = append(sent, i)
+	}
+	if len(sent) == 0 {
+		return results, nil
+	}
+
+	req := opensearchapi.MSearchReq{
+		Body: &body,
+	}
+	var osResp msearchResp
+	resp, err := cl.Do(ctx, req, &osResp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, osError(resp)
+	}
+	if len(osResp.Responses) != len(sent) {
+		return nil, serr.Wrap("unexpected number of responses", ErrOpensearchRequestFailed,
+			serr.Int("requests", len(sent)),
+			serr.Int("responses", len(osResp.Responses)))
+	}
+
+	for j, r := range osResp.Responses {
+		i := sent[j]
+		if r.Error != nil {
+			results[i].Err = serr.Wrap("search failed", ErrOpensearchRequestFailed,
+				serr.String("index", reqs[i].Index),
+				serr.Int("statusCode", r.Status),
+				serr.String("type", r.Error.Type),
+				serr.String("reason", r.Error.Reason))
+			continue
+		}
+		results[i].resp = &r.searchResp
+	}
+
+	return results, nil
+}
+
+// msearchLines returns the header and body lines of the search.
+func msearchLines(r MSearchRequest) ([]byte, error) {
+	query, err := buildQuery(r.Expr, r.OrderBy, r.Pag)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range r.Opts {
+		opt(query)
+	}
+
+	header := msearchHeader{Index: r.Index}
+	if query.ignoreUnavailable {
+		header.IgnoreUnavailable = &query.ignoreUnavailable
+	}
+	h, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	q, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 0, len(h)+len(q)+2)
+	b = append(b, h...)
+	b = append(b, '\n')
+	b = append(b, q...)
+	return append(b, '\n'), nil
+}
+
+// MSearchResponse decodes the result of one of the searches sent by [MSearch].
+// It returns [MSearchResult.Err] if the search failed.
+func MSearchResponse[T any](r MSearchResult) (*SearchResponse[T], error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	if r.resp == nil {
+		return nil, serr.Wrap("missing search response", ErrOpensearchRequestFailed)
+	}
+	return searchResponse[T](r.resp)
+}
+
+type msearchHeader struct {
+	Index             string `json:"index"`
+	IgnoreUnavailable *bool  `json:"ignore_unavailable,omitempty"`
+}
+
+type msearchResp struct {
+	Responses []msearchItem `json:"responses"`
+}
+
+type msearchItem struct {
+	searchResp
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
@@ -0,0 +1,119 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMSearch(t *testing.T) {
+	req := require.New(t)
+
+	var lines []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req.Equal("/_msearch", r.URL.Path)
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			var l map[string]any
+			req.NoError(json.Unmarshal(sc.Bytes(), &l))
+			lines = append(lines, l)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"responses":[
+			{"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_index":"orders","_id":"1","_source":{"name":"a"}}]},"status":200},
+			{"error":{"type":"index_not_found_exception","reason":"no such index [missing]"},"status":404},
+			{"hits":{"total":{"value":0,"relation":"eq"},"hits":[]},"aggregations":{"states":{"buckets":[{"key":"new","doc_count":3}]}},"status":200}
+		]}`)
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	results, err := MSearch(context.Background(), cl, []MSearchRequest{
+		{Index: "orders", Expr: Eq[string]{Ident: "name", Value: "a"}, OrderBy: "-createdAt", Pag: &Pagination{Size: 10}},
+		{Index: "missing", Expr: Eq[string]{Ident: "name", Value: "a"}},
+		{Index: "orders", Expr: Eq[string]{Ident: "name", Value: "a"}, Pag: &Pagination{}, Opts: []SearchOption{
+			WithAggregations(Aggs{"states": TermsAgg{Field: "state"}}),
+		}},
+	})
+	req.NoError(err)
+	req.Len(results, 3)
+
+	req.Len(lines, 6)
+	req.Equal(map[string]any{"index": "orders"}, lines[0])
+	req.Equal(float64(10), lines[1]["size"])
+	req.Equal([]any{map[string]any{"createdAt": map[string]any{"order": "desc"}}}, lines[1]["sort"])
+	req.Equal(map[string]any{"index": "missing"}, lines[2])
+	req.Contains(lines[5], "aggs")
+
+	type doc struct {
+		Name string `json:"name"`
+	}
+	res, err := MSearchResponse[doc](results[0])
+	req.NoError(err)
+	req.Equal(1, res.Total)
+	req.Equal("a", res.Docs[0].Document.Name)
+
+	_, err = MSearchResponse[doc](results[1])
+	req.ErrorIs(err, ErrOpensearchRequestFailed)
+
+	res, err = MSearchResponse[doc](results[2])
+	req.NoError(err)
+	req.Empty(res.Docs)
+	buckets, err := AggBuckets[string](res.Aggs, "states")
+	req.NoError(err)
+	req.Equal(3, buckets[0].DocCount)
+}
+
+func TestMSearchPerRequestErrors(t *testing.T) {
+	req := require.New(t)
+
+	var lines []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			var l map[string]any
+			req.NoError(json.Unmarshal(sc.Bytes(), &l))
+			lines = append(lines, l)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"responses":[{"hits":{"total":{"value":0,"relation":"eq"},"hits":[]},"status":200}]}`)
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	results, err := MSearch(context.Background(), cl, []MSearchRequest{
+		{Index: "orders", Expr: And{}, Pag: &Pagination{From: MaxResultWindow, Size: 10}},
+		{Index: "events-2026.10.17,events-2026.10.18", Expr: And{}, Opts: []SearchOption{WithIgnoreUnavailable()}},
+	})
+	req.NoError(err)
+	req.Len(results, 2)
+	req.ErrorIs(results[0].Err, ErrResultWindowExceeded)
+	req.NoError(results[1].Err)
+
+	req.Len(lines, 2)
+	req.Equal(map[string]any{"index": "events-2026.10.17,events-2026.10.18", "ignore_unavailable": true}, lines[0])
+
+	lines = nil
+	results, err = MSearch(context.Background(), cl, []MSearchRequest{
+		{Index: "orders", Expr: And{}, Pag: &Pagination{From: MaxResultWindow, Size: 10}},
+	})
+	req.NoError(err)
+	req.ErrorIs(results[0].Err, ErrResultWindowExceeded)
+	req.Empty(lines)
+}
+
+func TestMSearchResponseZeroResult(t *testing.T) {
+	_, err := MSearchResponse[struct{}](MSearchResult{})
+	require.ErrorIs(t, err, ErrOpensearchRequestFailed)
+}
@@ -0,0 +1,238 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+
+	"github.com/mailstepcz/serr"
+)
+
+// PitTiebreaker is the field appended to the sort of point in time searches to make the order of hits total.
+// Unlike _id, which requires fielddata, _shard_doc is cheap but available only with a point in time.
+const PitTiebreaker = "_shard_doc"
+
+var (
+	// ErrPitDeleteFailed represents an error from OpenSearch that point in time delete request was not successful.
+	ErrPitDeleteFailed = errors.New("OpenSearch point in time delete request failed")
+)
+
+// OpenPit creates a point in time on given index which is kept alive for [keepAlive] after each search.
+// When the point in time is no longer needed, [ClosePit] shall be called to free up resources.
+func OpenPit(ctx context.Context, cl *opensearch.Client, index string, keepAlive time.Duration) (string, error) {
+	var osResponse opensearchapi.PointInTimeCreateResp
+	resp, err := cl.Do(ctx, opensearchapi.PointInTimeCreateReq{
+		Indices: []string{index},
+		Params: opensearchapi.PointInTimeCreateParams{
+			KeepAlive: keepAlive,
+		},
+	}, &osResponse)
+	if err != nil {
+		return "", err
+	}
+	if resp.IsError() {
+		return "", osError(resp)
+	}
+	return osResponse.PitID, nil
+}
+
+// ClosePit frees up resources tied up to given point in time.
+func ClosePit(ctx context.Context, cl *opensearch.Client, pitID string) error {
+	var osResponse opensearchapi.PointInTimeDeleteResp
+	resp, err := cl.Do(ctx, opensearchapi.PointInTimeDeleteReq{
+		PitID: []string{pitID},
+	}, &osResponse)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return osError(resp)
+	}
+
+	for _, pit := range osResponse.Pits {
+		if !pit.Successful {
+			return serr.Wrap("", ErrPitDeleteFailed, serr.String("pitID", pit.PitID))
+		}
+	}
+
+	return nil
+}
+
+// PitScroll starts paging over the results of the expression using a point in time and search_after.
+// Unlike [Scroll], it's suitable for user-facing deep pagination. The [PitTiebreaker] field is appended
+// to the order to make paging stable. [WithIgnoreUnavailable] is rejected with [ErrOpensearchBadRequest]
+// since a point in time can't be opened over missing indices.
+func PitScroll[T any](ctx context.Context, cl *opensearch.Client, index string, expr Expr, orderBy string, size int, keepAlive time.Duration, opts ...SearchOption) (*PitScroller[T], error) {
+	query, err := buildQuery(expr, orderBy, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(query)
+	}
+	if query.ignoreUnavailable {
+		return nil, serr.Wrap("point in time over missing indices", ErrOpensearchBadRequest, serr.String("index", index))
+	}
+	query.addTiebreaker(PitTiebreaker)
+	query.Size = &size
+
+	pitID, err := OpenPit(ctx, cl, index, keepAlive)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PitScroller[T]{
+		query:     query,
+		pitID:     pitID,
+		size:      size,
+		cl:        cl,
+		keepAlive: keepAlive,
+	}
+	if err := s.loadNextPage(ctx); err != nil {
+		return nil, errors.Join(err, ClosePit(ctx, cl, pitID))
+	}
+
+	return s, nil
+}
+
+// PitScroller pages over the results using a point in time.
+type PitScroller[T any] struct {
+	docs      []IDedDocument[T]
+	query     *searchQuery
+	pitID     string
+	err       error
+	size      int
+	exhausted bool
+	cl        *opensearch.Client
+	keepAlive time.Duration
+	isClosed  bool
+}
+
+// Next returns true if there is at least one more doc for processing.
+// Next should be called before the [Doc] function.
+func (s *PitScroller[T]) Next(ctx context.Context) bool {
+	if s.err != nil {
+		return false
+	}
+
+	// there are still some docs in the stack.
+	if len(s.docs) != 0 {
+		return true
+	}
+
+	if s.exhausted {
+		return false
+	}
+
+	if err := s.loadNextPage(ctx); err != nil {
+		s.err = err
+		return false
+	}
+
+	return len(s.docs) != 0
+}
+
+// Doc returns next document for processing.
+func (s *PitScroller[T]) Doc() IDedDocument[T] {
+	if len(s.docs) == 0 {
+		panic("docs is an empty array, cannot access document from an empty array")
+	}
+	doc := s.docs[0]
+	s.docs = s.docs[1:]
+	return doc
+}
+
+// Docs returns all docs in current loaded page.
+func (s *PitScroller[T]) Docs() []IDedDocument[T] {
+	// nolint:makezero
+	results := make([]IDedDocument[T], len(s.docs))
+	copy(results, s.docs)
+	s.docs = nil
+
+	return results
+}
+
+// Error returns an error if error has occurred during the paging.
+func (s *PitScroller[T]) Error() error {
+	return s.err
+}
+
+// Close frees up resources tied up to the point in time.
+// Close is safe to call multiple times.
+func (s *PitScroller[T]) Close(ctx context.Context) error {
+	if s.isClosed {
+		return nil
+	}
+
+	if err := ClosePit(ctx, s.cl, s.pitID); err != nil {
+		s.err = err
+		return err
+	}
+
+	s.isClosed = true
+
+	return nil
+}
+
+func (s *PitScroller[T]) loadNextPage(ctx context.Context) error {
+	s.query.Pit = &searchPit{
+		ID:        s.pitID,
+		KeepAlive: strconv.FormatInt(s.keepAlive.Milliseconds(), 10) + "ms",
+	}
+
+	b, err := json.Marshal(s.query)
+	if err != nil {
+		return err
+	}
+
+	// the index is given by the point in time
+	req := opensearchapi.SearchReq{
+		Body: bytes.NewReader(b),
+	}
+	var osResponse searchResp
+	resp, err := s.cl.Do(ctx, req, &osResponse)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return osError(resp)
+	}
+
+	hits := osResponse.Hits.Hits
+	docs, err := mapDocs[T](hits)
+	if err != nil {
+		return err
+	}
+
+	s.docs = docs
+	s.exhausted = len(hits) < s.size
+	if len(hits) > 0 {
+		s.query.SearchAfter = docs[len(docs)-1].Sort
+	}
+	if osResponse.PitID != "" {
+		s.pitID = osResponse.PitID
+	}
+
+	return nil
+}
+
+// addTiebreaker appends the tiebreaker field to the sort so that search_after is stable.
+func (q *searchQuery) addTiebreaker(field string) {
+	for _, k := range q.Sort {
+		if k.Field == field {
+			return
+		}
+	}
+	q.Sort = append(q.Sort, SortKey{Field: field})
+}
+
+type searchPit struct {
+	ID        string `json:"id"`
+	KeepAlive string `json:"keep_alive"`
+}
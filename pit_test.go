@@ -0,0 +1,70 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPitScroller(t *testing.T) {
+	req := require.New(t)
+
+	pages := []string{
+		`{"pit_id":"pit-2","hits":{"hits":[{"_id":"1","_source":{"name":"a"},"sort":[1,"1"]},{"_id":"2","_source":{"name":"b"},"sort":[2,"2"]}]}}`,
+		`{"pit_id":"pit-3","hits":{"hits":[{"_id":"3","_source":{"name":"c"},"sort":[3,"3"]}]}}`,
+	}
+	var searchAfters []any
+	deleted := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/orders/_search/point_in_time":
+			req.Equal("60000ms", r.URL.Query().Get("keep_alive"))
+			_, _ = io.WriteString(w, `{"pit_id":"pit-1"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/_search":
+			var body map[string]any
+			req.NoError(json.NewDecoder(r.Body).Decode(&body))
+			searchAfters = append(searchAfters, body["search_after"])
+			sort, err := json.Marshal(body["sort"])
+			req.NoError(err)
+			req.JSONEq(`[{"createdAt":{"order":"asc"}},{"_shard_doc":{"order":"asc"}}]`, string(sort))
+			_, _ = io.WriteString(w, pages[0])
+			pages = pages[1:]
+		case r.Method == http.MethodDelete && r.URL.Path == "/_search/point_in_time":
+			deleted = true
+			_, _ = io.WriteString(w, `{"pits":[{"pit_id":"pit-3","successful":true}]}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	type doc struct {
+		Name string `json:"name"`
+	}
+	ctx := context.Background()
+	s, err := PitScroll[doc](ctx, cl, "orders", Eq[int]{Ident: "a", Value: 1}, "createdAt", 2, time.Minute)
+	req.NoError(err)
+
+	var names []string
+	for s.Next(ctx) {
+		names = append(names, s.Doc().Document.Name)
+	}
+	req.NoError(s.Error())
+	req.NoError(s.Close(ctx))
+	req.NoError(s.Close(ctx))
+
+	req.Equal([]string{"a", "b", "c"}, names)
+	req.Equal([]any{nil, []any{float64(2), "2"}}, searchAfters)
+	req.True(deleted)
+}
@@ -0,0 +1,348 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+
+	"github.com/mailstepcz/serr"
+)
+
+var (
+	// ErrReindexCountMismatch signifies that the new index doesn't contain the expected number of documents.
+	ErrReindexCountMismatch = errors.New("reindexed document count mismatch")
+	// ErrReindexMappingMismatch signifies that the new index of a resumed reindex has a mapping different from the configured one.
+	ErrReindexMappingMismatch = errors.New("existing new index has a different mapping")
+
+	// errReindexTaskRunning signifies that a failed server-side reindex couldn't be stopped,
+	// so the new index is kept lest the task recreated it with dynamic mappings.
+	errReindexTaskRunning = errors.New("reindex task may still be running")
+)
+
+const (
+	defaultReindexBatchSize    = 1000
+	defaultReindexPollInterval = 5 * time.Second
+	reindexScrollWindow        = 5 * time.Minute
+	reindexStopTimeout         = time.Minute
+)
+
+// ReindexConfig configures a blue/green reindex of the index behind an alias.
+type ReindexConfig struct {
+	// Alias is the alias switched to the new index.
+	Alias string
+	// Index is the new versioned index, e.g. orders-v7.
+	Index    string
+	Mapping  json.RawMessage
+	Settings json.RawMessage
+	// DeleteOld deletes the old index once the alias is switched.
+	DeleteOld bool
+	// BatchSize is the number of documents copied per batch, 1000 by default.
+	BatchSize int
+	// Slices is the number of slices of a server-side reindex; zero lets OpenSearch choose.
+	Slices int
+	// RequestsPerSecond throttles a server-side reindex.
+	RequestsPerSecond *int
+	// PollInterval is the interval of polling a server-side reindex task, 5s by default.
+	PollInterval time.Duration
+}
+
+// ReindexResult describes a finished reindex.
+// Resumed is true if the new index already existed, i.e. a previous run was interrupted.
+type ReindexResult struct {
+	OldIndex string
+	Index    string
+	Copied   int
+	Resumed  bool
+}
+
+// Reindex moves the alias to a new index, copying the data by the server-side _reindex API.
+//
+// The new index is created with the mapping and settings, the documents of the index behind the alias are copied into it,
+// the document counts of both indices are checked and the alias is switched atomically. If the alias doesn't exist yet,
+// the new index is just created and the alias is set to it. Writes to the old index should be paused during the reindex,
+// otherwise the counts may differ.
+//
+// If the process is interrupted, running Reindex again with the same configuration resumes the copying into the existing new index;
+// documents already copied are overwritten only by newer versions. If the mapping is set, the resumed index must have
+// the same mapping, otherwise [ErrReindexMappingMismatch] is returned. On an error, the reindex task is cancelled and
+// the new index is deleted, unless it was resumed or the alias already points to it.
+func Reindex(ctx context.Context, client *opensearchapi.Client, cfg ReindexConfig) (*ReindexResult, error) {
+	return reindex(ctx, client, cfg, func(ctx context.Context, oldIndex string) (int, int, error) {
+		n, err := serverReindex(ctx, client, cfg, oldIndex)
+		return n, 0, err
+	})
+}
+
+// ReindexWith is like [Reindex] but copies the data by scrolling the old index and bulk-loading the new one.
+// Each document is passed through the transform function; if it returns nil, the document is skipped.
+// Unlike [Reindex], a resumed copying overwrites the documents already copied.
+func ReindexWith[T any](ctx context.Context, client *opensearchapi.Client, cfg ReindexConfig, transform func(IDedDocument[T]) (*T, error)) (*ReindexResult, error) {
+	return reindex(ctx, client, cfg, func(ctx context.Context, oldIndex string) (int, int, error) {
+		return clientReindex(ctx, client.Client, cfg, oldIndex, transform)
+	})
+}
+
+// copyFunc copies the documents from the old index into the new one.
+// It returns the number of copied documents and the number of documents skipped on purpose.
+type copyFunc func(ctx context.Context, oldIndex string) (int, int, error)
+
+func reindex(ctx context.Context, client *opensearchapi.Client, cfg ReindexConfig, copyDocs copyFunc) (*ReindexResult, error) {
+	res := ReindexResult{Index: cfg.Index}
+
+	var oldIndex string
+	alias, err := AliasGet(ctx, client, cfg.Alias)
+	switch {
+	case errors.Is(err, ErrAliasNotFound):
+	case err != nil:
+		return nil, serr.Wrap("getting alias", err, serr.String("alias", cfg.Alias))
+	case alias.Index == cfg.Index:
+		// a previous run has already switched the alias
+		return &res, nil
+	default:
+		oldIndex = alias.Index
+	}
+	res.OldIndex = oldIndex
+
+	res.Resumed, err = IndexExists(ctx, client, cfg.Index)
+	if err != nil {
+		return nil, err
+	}
+	if res.Resumed {
+		if err := reindexCheckMapping(ctx, client, cfg); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := IndexCreate(ctx, client, cfg.Index, cfg.Mapping, cfg.Settings); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := reindexSwitch(ctx, client, cfg, oldIndex, copyDocs, &res); err != nil {
+		if res.Resumed {
+			// the index holds the progress of the previous runs
+			return nil, err
+		}
+		return nil, errors.Join(err, reindexCleanup(context.WithoutCancel(ctx), client, cfg, err))
+	}
+
+	if oldIndex != "" && cfg.DeleteOld {
+		if err := IndexDelete(ctx, client, oldIndex); err != nil {
+			return &res, err
+		}
+	}
+
+	return &res, nil
+}
+
+func reindexSwitch(ctx context.Context, client *opensearchapi.Client, cfg ReindexConfig, oldIndex string, copyDocs copyFunc, res *ReindexResult) error {
+	if oldIndex == "" {
+		return AliasSet(ctx, client, cfg.Index, cfg.Alias)
+	}
+
+	copied, skipped, err := copyDocs(ctx, oldIndex)
+	if err != nil {
+		return serr.Wrap("copying documents", err, serr.String("oldIndex", oldIndex), serr.String("index", cfg.Index))
+	}
+	res.Copied = copied
+
+	if err := IndexRefresh(ctx, client, cfg.Index); err != nil {
+		return err
+	}
+	oldCount, err := Count(ctx, client.Client, oldIndex, And{})
+	if err != nil {
+		return err
+	}
+	newCount, err := Count(ctx, client.Client, cfg.Index, And{})
+	if err != nil {
+		return err
+	}
+	if newCount != oldCount-skipped {
+		return serr.Wrap("", ErrReindexCountMismatch,
+			serr.String("oldIndex", oldIndex),
+			serr.String("index", cfg.Index),
+			serr.Int("oldCount", oldCount),
+			serr.Int("skipped", skipped),
+			serr.Int("newCount", newCount))
+	}
+
+	return AliasSwitch(ctx, client, cfg.Alias, cfg.Index)
+}
+
+// reindexCheckMapping checks that the existing new index has the configured mapping, if any.
+func reindexCheckMapping(ctx context.Context, client *opensearchapi.Client, cfg ReindexConfig) error {
+	if cfg.Mapping == nil {
+		return nil
+	}
+	current, err := IndexGetMapping(ctx, client, cfg.Index)
+	if err != nil {
+		return err
+	}
+	changes, err := DiffMappings(current, cfg.Mapping)
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		return serr.Wrap("", ErrReindexMappingMismatch,
+			serr.String("index", cfg.Index),
+			serr.String("field", changes[0].Field),
+			serr.String("reason", changes[0].Reason))
+	}
+	return nil
+}
+
+// reindexCleanup deletes the new index after a failed reindex. The index is kept if the alias points to it,
+// e.g. when the alias switch failed to be acknowledged, if the alias can't be read, or if a reindex task may still be running.
+func reindexCleanup(ctx context.Context, client *opensearchapi.Client, cfg ReindexConfig, cause error) error {
+	if errors.Is(cause, errReindexTaskRunning) {
+		return nil
+	}
+
+	alias, err := AliasGet(ctx, client, cfg.Alias)
+	switch {
+	case errors.Is(err, ErrAliasNotFound):
+	case err != nil:
+		return serr.Wrap("getting alias before cleaning up new index", err, serr.String("alias", cfg.Alias))
+	case alias.Index == cfg.Index:
+		return nil
+	}
+
+	if err := IndexDelete(ctx, client, cfg.Index); err != nil {
+		return serr.Wrap("cleaning up new index", err)
+	}
+	return nil
+}
+
+func serverReindex(ctx context.Context, client *opensearchapi.Client, cfg ReindexConfig, oldIndex string) (int, error) {
+	b, err := json.Marshal(reindexBody{
+		Conflicts: "proceed",
+		Source:    reindexSource{Index: oldIndex, Size: cfg.BatchSize},
+		// external versioning makes a resumed reindex skip the documents already copied
+		Dest: reindexDest{Index: cfg.Index, VersionType: "external"},
+	})
+	if err != nil {
+		return 0, serr.Wrap("marshalling reindex body", err)
+	}
+
+	wait := false
+	params := opensearchapi.ReindexParams{
+		RequestsPerSecond: cfg.RequestsPerSecond,
+		WaitForCompletion: &wait,
+	}
+	if cfg.Slices > 0 {
+		params.Slices = cfg.Slices
+	} else {
+		params.Slices = "auto"
+	}
+	resp, err := client.Reindex(ctx, opensearchapi.ReindexReq{
+		Body:   bytes.NewReader(b),
+		Params: params,
+	})
+	if err != nil {
+		return 0, serr.Wrap("starting reindex", err, serr.String("oldIndex", oldIndex), serr.String("index", cfg.Index))
+	}
+
+	pollInterval := cfg.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultReindexPollInterval
+	}
+	task := NewTask(client.Client, TaskID(resp.Task))
+	st, err := task.Wait(ctx, pollInterval)
+	if err != nil {
+		if st == nil || !st.Completed {
+			// the task would keep writing into the new index, which is deleted on an error
+			err = errors.Join(err, stopReindexTask(context.WithoutCancel(ctx), task, pollInterval))
+		}
+		return 0, err
+	}
+
+	return st.Progress.Created + st.Progress.Updated, nil
+}
+
+// stopReindexTask cancels the task and waits until it stops.
+func stopReindexTask(ctx context.Context, task *Task, pollInterval time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, reindexStopTimeout)
+	defer cancel()
+
+	if err := task.Cancel(ctx); err != nil {
+		if errors.Is(err, ErrTaskNotFound) {
+			return nil
+		}
+		return serr.Wrap("cancelling reindex task", errors.Join(errReindexTaskRunning, err), serr.String("taskID", string(task.ID)))
+	}
+	// the status of a cancelled task carries the cancellation error
+	if st, err := task.Wait(ctx, pollInterval); st == nil || !st.Completed {
+		return serr.Wrap("waiting for cancelled reindex task", errors.Join(errReindexTaskRunning, err), serr.String("taskID", string(task.ID)))
+	}
+	return nil
+}
+
+func clientReindex[T any](ctx context.Context, cl *opensearch.Client, cfg ReindexConfig, oldIndex string, transform func(IDedDocument[T]) (*T, error)) (int, int, error) {
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultReindexBatchSize
+	}
+	scroller, err := Scroll[T](ctx, cl, oldIndex, And{}, SortDoc, batchSize, reindexScrollWindow)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = scroller.Close(context.WithoutCancel(ctx)) }()
+
+	var copied, skipped int
+	for scroller.Next(ctx) {
+		docs := scroller.Docs()
+		ops := make([]BulkOperation[T], 0, len(docs))
+		for _, d := range docs {
+			doc, err := transform(d)
+			if err != nil {
+				return 0, 0, serr.Wrap("transforming document", err, serr.String("id", d.ID))
+			}
+			if doc == nil {
+				skipped++
+				continue
+			}
+			ops = append(ops, BulkOperation[T]{
+				OperationType: OpIndex,
+				ID:            d.ID,
+				Index:         cfg.Index,
+				Doc:           doc,
+			})
+		}
+		if len(ops) == 0 {
+			continue
+		}
+		res, err := Bulk(ctx, cl, ops, WithBulkRetry(RetryPolicy{}))
+		if err != nil {
+			return 0, 0, err
+		}
+		if err := res.Err(); err != nil {
+			return 0, 0, err
+		}
+		copied += len(ops)
+	}
+	if err := scroller.Error(); err != nil {
+		return 0, 0, err
+	}
+
+	return copied, skipped, nil
+}
+
+type reindexBody struct {
+	Conflicts string        `json:"conflicts"`
+	Source    reindexSource `json:"source"`
+	Dest      reindexDest   `json:"dest"`
+}
+
+type reindexSource struct {
+	Index string `json:"index"`
+	Size  int    `json:"size,omitempty"`
+}
+
+type reindexDest struct {
+	Index       string `json:"index"`
+	VersionType string `json:"version_type,omitempty"`
+}
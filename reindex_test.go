@@ -0,0 +1,285 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCluster answers the requests of a reindex of orders-v6 into orders-v7 behind the orders alias.
+type fakeCluster struct {
+	mu          sync.Mutex
+	calls       []string
+	aliasIndex  string
+	newExists   bool
+	counts      map[string]int
+	reindexBody map[string]any
+	bulkIDs     []string
+	mapping     string
+	newMapping  string
+	settings    string
+	createBody  map[string]any
+	// switchUnacknowledged makes the alias switch take effect without being acknowledged.
+	switchUnacknowledged bool
+	// taskRunning makes the reindex task run until it's cancelled; onTaskPoll is called on each poll.
+	taskRunning   bool
+	taskCancelled bool
+	onTaskPoll    func()
+}
+
+func (c *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	call := r.Method + " " + r.URL.Path
+	c.calls = append(c.calls, call)
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case call == "GET /_cat/aliases/orders":
+		if c.aliasIndex == "" {
+			_, _ = io.WriteString(w, `[]`)
+			return
+		}
+		_, _ = io.WriteString(w, `[{"alias":"orders","index":"`+c.aliasIndex+`"}]`)
+	case call == "HEAD /orders-v7":
+		if !c.newExists {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case call == "PUT /orders-v7":
+		_ = json.NewDecoder(r.Body).Decode(&c.createBody)
+		c.newExists = true
+		_, _ = io.WriteString(w, `{"acknowledged":true,"index":"orders-v7"}`)
+	case call == "POST /_reindex":
+		_ = json.NewDecoder(r.Body).Decode(&c.reindexBody)
+		_, _ = io.WriteString(w, `{"task":"node-1:7"}`)
+	case call == "GET /_tasks/node-1:7":
+		if c.onTaskPoll != nil {
+			c.onTaskPoll()
+		}
+		switch {
+		case c.taskCancelled:
+			_, _ = io.WriteString(w, `{"completed":true,"task":{"cancelled":true},"error":{"type":"task_cancelled_exception","reason":"by user request"}}`)
+		case c.taskRunning:
+			_, _ = io.WriteString(w, `{"completed":false,"task":{"status":{"total":3,"created":1,"batches":1}}}`)
+		default:
+			_, _ = io.WriteString(w, `{"completed":true,"task":{"status":{"total":3,"created":3,"batches":1}},"response":{"failures":[]}}`)
+		}
+	case call == "POST /_tasks/node-1:7/_cancel":
+		c.taskCancelled = true
+		_, _ = io.WriteString(w, `{"nodes":{}}`)
+	case call == "POST /orders-v6/_search":
+		_, _ = io.WriteString(w, `{"_scroll_id":"s1","hits":{"total":{"value":3,"relation":"eq"},"hits":[
+			{"_id":"1","_source":{"name":"a"}},{"_id":"2","_source":{"name":"b"}},{"_id":"3","_source":{"name":"c"}}]}}`)
+	case call == "DELETE /_search/scroll":
+		_, _ = io.WriteString(w, `{"succeeded":true,"num_freed":1}`)
+	case call == "POST /_bulk":
+		sc := bufio.NewScanner(r.Body)
+		var items []string
+		for sc.Scan() {
+			var action struct {
+				Index *struct {
+					ID string `json:"_id"`
+				} `json:"index"`
+			}
+			if err := json.Unmarshal(sc.Bytes(), &action); err == nil && action.Index != nil {
+				c.bulkIDs = append(c.bulkIDs, action.Index.ID)
+				items = append(items, `{"index":{"_index":"orders-v7","_id":"`+action.Index.ID+`","status":201}}`)
+			}
+		}
+		_, _ = io.WriteString(w, `{"errors":false,"items":[`+strings.Join(items, ",")+`]}`)
+	case call == "POST /orders-v7/_refresh":
+		_, _ = io.WriteString(w, `{"_shards":{"total":1,"successful":1,"failed":0}}`)
+	case strings.HasSuffix(call, "/_count"):
+		index := strings.TrimSuffix(strings.TrimPrefix(call, "POST /"), "/_count")
+		fmt.Fprintf(w, `{"count":%d}`, c.counts[index])
+	case call == "GET /orders/_mapping":
+		_, _ = io.WriteString(w, `{"`+c.aliasIndex+`":{"mappings":`+c.mapping+`}}`)
+	case call == "GET /orders-v7/_mapping":
+		_, _ = io.WriteString(w, `{"orders-v7":{"mappings":`+c.newMapping+`}}`)
+	case call == "GET /orders-v6/_settings":
+		_, _ = io.WriteString(w, `{"orders-v6":{"settings":`+c.settings+`}}`)
+	case call == "PUT /orders-v6/_mapping":
+		_, _ = io.WriteString(w, `{"acknowledged":true}`)
+	case call == "POST /_aliases":
+		c.aliasIndex = "orders-v7"
+		fmt.Fprintf(w, `{"acknowledged":%t}`, !c.switchUnacknowledged)
+	case call == "DELETE /orders-v6", call == "DELETE /orders-v7":
+		_, _ = io.WriteString(w, `{"acknowledged":true}`)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"error":"unexpected call `+call+`"}`)
+	}
+}
+
+func newFakeClusterClient(t *testing.T, c *fakeCluster) *opensearchapi.Client {
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+	client, err := opensearchapi.NewClient(opensearchapi.Config{Client: opensearch.Config{Addresses: []string{srv.URL}}})
+	require.NoError(t, err)
+	return client
+}
+
+func TestReindex(t *testing.T) {
+	req := require.New(t)
+
+	c := &fakeCluster{aliasIndex: "orders-v6", counts: map[string]int{"orders-v6": 3, "orders-v7": 3}}
+	client := newFakeClusterClient(t, c)
+
+	res, err := Reindex(context.Background(), client, ReindexConfig{
+		Alias:        "orders",
+		Index:        "orders-v7",
+		Mapping:      json.RawMessage(`{"properties":{"name":{"type":"keyword"}}}`),
+		Settings:     json.RawMessage(`{}`),
+		DeleteOld:    true,
+		PollInterval: time.Millisecond,
+	})
+	req.NoError(err)
+	req.Equal(&ReindexResult{OldIndex: "orders-v6", Index: "orders-v7", Copied: 3}, res)
+	req.Equal([]string{
+		"GET /_cat/aliases/orders",
+		"HEAD /orders-v7",
+		"PUT /orders-v7",
+		"POST /_reindex",
+		"GET /_tasks/node-1:7",
+		"POST /orders-v7/_refresh",
+		"POST /orders-v6/_count",
+		"POST /orders-v7/_count",
+		"GET /_cat/aliases/orders",
+		"POST /_aliases",
+		"DELETE /orders-v6",
+	}, c.calls)
+	req.Equal(map[string]any{
+		"conflicts": "proceed",
+		"source":    map[string]any{"index": "orders-v6"},
+		"dest":      map[string]any{"index": "orders-v7", "version_type": "external"},
+	}, c.reindexBody)
+}
+
+func TestReindexCountMismatch(t *testing.T) {
+	req := require.New(t)
+
+	c := &fakeCluster{aliasIndex: "orders-v6", counts: map[string]int{"orders-v6": 3, "orders-v7": 2}}
+	client := newFakeClusterClient(t, c)
+
+	_, err := Reindex(context.Background(), client, ReindexConfig{Alias: "orders", Index: "orders-v7", PollInterval: time.Millisecond})
+	req.ErrorIs(err, ErrReindexCountMismatch)
+	req.NotContains(c.calls, "POST /_aliases")
+	req.Equal("DELETE /orders-v7", c.calls[len(c.calls)-1])
+}
+
+func TestReindexResumed(t *testing.T) {
+	req := require.New(t)
+
+	cfg := ReindexConfig{
+		Alias:        "orders",
+		Index:        "orders-v7",
+		Mapping:      json.RawMessage(`{"properties":{"name":{"type":"keyword"}}}`),
+		PollInterval: time.Millisecond,
+	}
+
+	c := &fakeCluster{aliasIndex: "orders-v6", newExists: true, newMapping: `{"properties":{"name":{"type":"keyword"}}}`,
+		counts: map[string]int{"orders-v6": 3, "orders-v7": 2}}
+	client := newFakeClusterClient(t, c)
+	_, err := Reindex(context.Background(), client, cfg)
+	req.ErrorIs(err, ErrReindexCountMismatch)
+	req.Equal([]string{
+		"GET /_cat/aliases/orders",
+		"HEAD /orders-v7",
+		"GET /orders-v7/_mapping",
+		"POST /_reindex",
+		"GET /_tasks/node-1:7",
+		"POST /orders-v7/_refresh",
+		"POST /orders-v6/_count",
+		"POST /orders-v7/_count",
+	}, c.calls)
+
+	c = &fakeCluster{aliasIndex: "orders-v6", newExists: true, newMapping: `{"properties":{"name":{"type":"text"}}}`}
+	client = newFakeClusterClient(t, c)
+	_, err = Reindex(context.Background(), client, cfg)
+	req.ErrorIs(err, ErrReindexMappingMismatch)
+	req.Equal([]string{"GET /_cat/aliases/orders", "HEAD /orders-v7", "GET /orders-v7/_mapping"}, c.calls)
+}
+
+func TestReindexSwitchUnacknowledged(t *testing.T) {
+	req := require.New(t)
+
+	c := &fakeCluster{aliasIndex: "orders-v6", counts: map[string]int{"orders-v6": 3, "orders-v7": 3}, switchUnacknowledged: true}
+	client := newFakeClusterClient(t, c)
+
+	_, err := Reindex(context.Background(), client, ReindexConfig{Alias: "orders", Index: "orders-v7", DeleteOld: true, PollInterval: time.Millisecond})
+	req.Error(err)
+	// the alias points to the new index, which must survive
+	req.Equal("GET /_cat/aliases/orders", c.calls[len(c.calls)-1])
+	req.NotContains(c.calls, "DELETE /orders-v7")
+	req.NotContains(c.calls, "DELETE /orders-v6")
+}
+
+func TestReindexCancelled(t *testing.T) {
+	req := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &fakeCluster{aliasIndex: "orders-v6", taskRunning: true, onTaskPoll: cancel}
+	client := newFakeClusterClient(t, c)
+
+	_, err := Reindex(ctx, client, ReindexConfig{Alias: "orders", Index: "orders-v7", PollInterval: time.Millisecond})
+	req.ErrorIs(err, context.Canceled)
+	req.Equal([]string{
+		"GET /_cat/aliases/orders",
+		"HEAD /orders-v7",
+		"PUT /orders-v7",
+		"POST /_reindex",
+		"GET /_tasks/node-1:7",
+		"POST /_tasks/node-1:7/_cancel",
+		"GET /_tasks/node-1:7",
+		"GET /_cat/aliases/orders",
+		"DELETE /orders-v7",
+	}, c.calls)
+}
+
+func TestReindexWith(t *testing.T) {
+	req := require.New(t)
+
+	c := &fakeCluster{aliasIndex: "orders-v6", counts: map[string]int{"orders-v6": 3, "orders-v7": 2}}
+	client := newFakeClusterClient(t, c)
+
+	type doc struct {
+		Name string `json:"name"`
+	}
+	res, err := ReindexWith(context.Background(), client, ReindexConfig{Alias: "orders", Index: "orders-v7"},
+		func(d IDedDocument[doc]) (*doc, error) {
+			if d.ID == "2" {
+				return nil, nil
+			}
+			return &doc{Name: strings.ToUpper(d.Document.Name)}, nil
+		})
+	req.NoError(err)
+	req.Equal(2, res.Copied)
+	req.Equal([]string{"1", "3"}, c.bulkIDs)
+	req.Contains(c.calls, "POST /_aliases")
+	req.NotContains(c.calls, "DELETE /orders-v6")
+}
+
+func TestReindexNewAlias(t *testing.T) {
+	req := require.New(t)
+
+	c := &fakeCluster{}
+	client := newFakeClusterClient(t, c)
+
+	res, err := Reindex(context.Background(), client, ReindexConfig{Alias: "orders", Index: "orders-v7"})
+	req.NoError(err)
+	req.Equal(&ReindexResult{Index: "orders-v7"}, res)
+	req.Equal([]string{"GET /_cat/aliases/orders", "HEAD /orders-v7", "PUT /orders-v7", "POST /_aliases"}, c.calls)
+}
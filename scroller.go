@@ -7,6 +7,20 @@ import (
 	"github.com/opensearch-project/opensearch-go/v4"
 )
 
+// DocIterator iterates over search results in batches. It's implemented by [Scroller] and [PitScroller].
+type DocIterator[T any] interface {
+	Next(ctx context.Context) bool
+	Doc() IDedDocument[T]
+	Docs() []IDedDocument[T]
+	Error() error
+	Close(ctx context.Context) error
+}
+
+var (
+	_ DocIterator[any] = new(Scroller[any])
+	_ DocIterator[any] = new(PitScroller[any])
+)
+
 // Scroller scrolls over the results.
 type Scroller[T any] struct {
 	docs         []IDedDocument[T]
@@ -6,7 +6,6 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"net/http"
 	"time"
@@ -203,14 +202,25 @@ func updateDoc(ctx context.Context, cl *opensearch.Client, index, id string, b [
 }
 
 // Delete deletes a document.
-func Delete(ctx context.Context, cl *opensearch.Client, index, id string) error {
-	return deleteDoc(ctx, cl, index, id, nil)
+func Delete(ctx context.Context, cl *opensearch.Client, index, id string, opts ...DeleteOption) error {
+	var params *opensearchapi.DocumentDeleteParams
+	if len(opts) > 0 {
+		params = new(opensearchapi.DocumentDeleteParams)
+		for _, opt := range opts {
+			opt(params)
+		}
+	}
+	return deleteDoc(ctx, cl, index, id, params)
 }
 
 // DeleteWithRefresh deletes a document with refresh = true parameter.
 // https://opensearch.org/docs/latest/api-reference/document-apis/delete-document/#query-parameters
-func DeleteWithRefresh(ctx context.Context, cl *opensearch.Client, index, id string) error {
-	return deleteDoc(ctx, cl, index, id, &opensearchapi.DocumentDeleteParams{Refresh: "true"})
+func DeleteWithRefresh(ctx context.Context, cl *opensearch.Client, index, id string, opts ...DeleteOption) error {
+	params := &opensearchapi.DocumentDeleteParams{Refresh: "true"}
+	for _, opt := range opts {
+		opt(params)
+	}
+	return deleteDoc(ctx, cl, index, id, params)
 }
 
 func deleteDoc(ctx context.Context, cl *opensearch.Client, index, id string, params *opensearchapi.DocumentDeleteParams) error {
@@ -231,17 +241,33 @@ func deleteDoc(ctx context.Context, cl *opensearch.Client, index, id string, par
 		if resp.StatusCode == http.StatusNotFound {
 			return errors.Join(ErrDocumentNotFound, osError(resp))
 		}
+		if resp.StatusCode == http.StatusConflict {
+			return errors.Join(ErrDocumentHasNewerVersion, osError(resp))
+		}
 		return osError(resp)
 	}
 	return nil
 }
 
 // Get gets a document.
-func Get[T any](ctx context.Context, cl *opensearch.Client, index, id string) (*T, error) {
+func Get[T any](ctx context.Context, cl *opensearch.Client, index, id string, opts ...GetOption) (*T, error) {
+	doc, err := GetWithMeta[T](ctx, cl, index, id, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Document, nil
+}
+
+// GetWithMeta gets a document along with its metadata.
+// The sequence number and primary term can be used for optimistic concurrency control.
+func GetWithMeta[T any](ctx context.Context, cl *opensearch.Client, index, id string, opts ...GetOption) (*IDedDocument[T], error) {
 	req := opensearchapi.DocumentGetReq{
 		Index:      index,
 		DocumentID: id,
 	}
+	for _, opt := range opts {
+		opt(&req.Params)
+	}
 	var sresp opensearchapi.DocumentGetResp
 	resp, err := cl.Do(ctx, req, &sresp)
 	if err != nil {
@@ -254,53 +280,160 @@ func Get[T any](ctx context.Context, cl *opensearch.Client, index, id string) (*
 		return nil, ErrDocumentNotFound
 	}
 	var doc T
-	if err := json.Unmarshal(sresp.Source, &doc); err != nil {
-		return nil, err
+	if len(sresp.Source) != 0 {
+		if err := json.Unmarshal(sresp.Source, &doc); err != nil {
+			return nil, err
+		}
+	}
+	var fields map[string][]any
+	if len(sresp.Fields) != 0 {
+		if err := json.Unmarshal(sresp.Fields, &fields); err != nil {
+			return nil, err
+		}
+	}
+	return &IDedDocument[T]{
+		ID:          sresp.ID,
+		Document:    &doc,
+		Index:       sresp.Index,
+		Version:     sresp.Version,
+		SeqNo:       sresp.SeqNo,
+		PrimaryTerm: sresp.PrimaryTerm,
+		Fields:      fields,
+	}, nil
+}
+
+// ReadModifyWrite gets the document, modifies it and indexes it back unless it has been changed in the meantime.
+// On such conflict, the whole cycle is repeated up to maxAttempts times in total.
+// A [VersionedDocument] is rejected with [ErrOpensearchBadRequest] since OpenSearch doesn't accept
+// an external version together with the sequence number condition.
+func ReadModifyWrite[T any](ctx context.Context, cl *opensearch.Client, index, id string, maxAttempts int, modify func(*T) error, params ...IndexParam) error {
+	if _, ok := any((*T)(nil)).(VersionedDocument); ok {
+		return serr.Wrap("read-modify-write of a versioned document", ErrOpensearchBadRequest, serr.String("index", index), serr.String("id", id))
+	}
+
+	var err error
+	for range max(maxAttempts, 1) {
+		var doc *IDedDocument[T]
+		doc, err = GetWithMeta[T](ctx, cl, index, id)
+		if err != nil {
+			return err
+		}
+		if err := modify(doc.Document); err != nil {
+			return err
+		}
+		err = indexDoc(ctx, cl, index, id, doc.Document, append(params, WithIndexParamIfSeqNo(doc.SeqNo, doc.PrimaryTerm))...)
+		if !errors.Is(err, ErrDocumentHasNewerVersion) {
+			return err
+		}
 	}
-	return &doc, err
+	return err
 }
 
 // Search searches for documents.
 // The orderBy argument is the column by which to order the results. A hyphen at its beginning signifies descending order.
-func Search[T any](ctx context.Context, cl *opensearch.Client, index string, expr Expr, orderBy string, pag *Pagination) ([]IDedDocument[T], int, error) {
-	query, err := buildQuery(expr, orderBy, pag)
+// Several columns can be separated by commas, see [ParseSort]; use [WithSort] for advanced sorting.
+// Expressions wrapped in [Filter] are put into the non-scoring filter context.
+func Search[T any](ctx context.Context, cl *opensearch.Client, index string, expr Expr, orderBy string, pag *Pagination, opts ...SearchOption) ([]IDedDocument[T], int, error) {
+	res, err := SearchWithResponse[T](ctx, cl, index, expr, orderBy, pag, opts...)
 	if err != nil {
 		return nil, 0, err
 	}
+	return res.Docs, res.Total, nil
+}
+
+// SearchWithResponse searches for documents like [Search] and returns the whole search response.
+func SearchWithResponse[T any](ctx context.Context, cl *opensearch.Client, index string, expr Expr, orderBy string, pag *Pagination, opts ...SearchOption) (*SearchResponse[T], error) {
+	query, err := buildQuery(expr, orderBy, pag)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(query)
+	}
 
 	b, err := json.Marshal(query)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
 	content := bytes.NewReader(b)
 	req := opensearchapi.SearchReq{
 		Indices: []string{index},
 		Body:    content,
-		Params:  opensearchapi.SearchParams{},
+		Params:  searchParams(query),
 	}
-	var osResp opensearchapi.SearchResp
+	var osResp searchResp
 	resp, err := cl.Do(ctx, req, &osResp)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 	if resp.IsError() {
-		return nil, 0, osError(resp)
+		return nil, osError(resp)
 	}
 
-	total := osResp.Hits.Total.Value
+	return searchResponse[T](&osResp)
+}
 
+func searchResponse[T any](osResp *searchResp) (*SearchResponse[T], error) {
 	docs, err := mapDocs[T](osResp.Hits.Hits)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+
+	res := SearchResponse[T]{
+		Docs:       docs,
+		Total:      osResp.Hits.Total.Value,
+		TotalExact: osResp.Hits.Total.Relation == TotalRelationEq,
+	}
+	if len(osResp.Aggregations) > 0 {
+		res.Aggs = new(AggResults)
+		if err := json.Unmarshal(osResp.Aggregations, res.Aggs); err != nil {
+			return nil, err
+		}
+	}
+
+	return &res, nil
+}
+
+// Count counts the documents matching the expression.
+// Unlike the total of [Search], the count is always exact. Of the options, only [WithIgnoreUnavailable] applies.
+func Count(ctx context.Context, cl *opensearch.Client, index string, expr Expr, opts ...SearchOption) (int, error) {
+	query, err := buildQuery(expr, "", nil)
+	if err != nil {
+		return 0, err
+	}
+	for _, opt := range opts {
+		opt(query)
+	}
+
+	b, err := json.Marshal(struct {
+		Query searchBool `json:"query"`
+	}{query.Query})
+	if err != nil {
+		return 0, err
 	}
 
-	return docs, total, nil
+	req := opensearchapi.IndicesCountReq{
+		Indices: []string{index},
+		Body:    bytes.NewReader(b),
+	}
+	if query.ignoreUnavailable {
+		req.Params.IgnoreUnavailable = &query.ignoreUnavailable
+	}
+	var osResp opensearchapi.IndicesCountResp
+	resp, err := cl.Do(ctx, req, &osResp)
+	if err != nil {
+		return 0, err
+	}
+	if resp.IsError() {
+		return 0, osError(resp)
+	}
+	return osResp.Count, nil
 }
 
 // Scroll starts new scroll on given index.
-func Scroll[T any](ctx context.Context, cl *opensearch.Client, index string, expr Expr, orderBy string, size int, scrollWindow time.Duration) (*Scroller[T], error) {
-	res, err := StartScroll[T](ctx, cl, index, expr, orderBy, size, scrollWindow)
+func Scroll[T any](ctx context.Context, cl *opensearch.Client, index string, expr Expr, orderBy string, size int, scrollWindow time.Duration, opts ...SearchOption) (*Scroller[T], error) {
+	res, err := StartScroll[T](ctx, cl, index, expr, orderBy, size, scrollWindow, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -310,13 +443,16 @@ func Scroll[T any](ctx context.Context, cl *opensearch.Client, index string, exp
 // StartScroll starts new scroll that will returns results in batches of [size].
 // Scroll is stable, and will be stable for given [ScrollWindow].
 // When scroll is completed [StopScroll] to free up resources otherwise resources.
-func StartScroll[T any](ctx context.Context, cl *opensearch.Client, index string, expr Expr, orderBy string, size int, scrollWindow time.Duration) (*ScrollResponse[T], error) {
+func StartScroll[T any](ctx context.Context, cl *opensearch.Client, index string, expr Expr, orderBy string, size int, scrollWindow time.Duration, opts ...SearchOption) (*ScrollResponse[T], error) {
 	query, err := buildQuery(expr, orderBy, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	query.Size = &size
+	for _, opt := range opts {
+		opt(query)
+	}
 
 	b, err := json.Marshal(query)
 	if err != nil {
@@ -327,11 +463,10 @@ func StartScroll[T any](ctx context.Context, cl *opensearch.Client, index string
 	req := opensearchapi.SearchReq{
 		Indices: []string{index},
 		Body:    content,
-		Params: opensearchapi.SearchParams{
-			Scroll: scrollWindow,
-		},
+		Params:  searchParams(query),
 	}
-	var osResponse opensearchapi.SearchResp
+	req.Params.Scroll = scrollWindow
+	var osResponse searchResp
 	resp, err := cl.Do(ctx, req, &osResponse)
 	if err != nil {
 		return nil, err
@@ -362,7 +497,7 @@ func StartScroll[T any](ctx context.Context, cl *opensearch.Client, index string
 // NextScroll returns next batch of results. New scroll id can be returned.
 // When scroll is completed [StopScroll] to free up resources otherwise resources.
 func NextScroll[T any](ctx context.Context, cl *opensearch.Client, scrollID string, scrollWindow time.Duration) (*ScrollResponse[T], error) {
-	var osResponse opensearchapi.ScrollGetResp
+	var osResponse searchResp
 	resp, err := cl.Do(ctx, opensearchapi.ScrollGetReq{
 		ScrollID: scrollID,
 		Params: opensearchapi.ScrollGetParams{
@@ -427,16 +562,40 @@ func osError(resp *opensearch.Response) error {
 	return serr.Wrap("search failed", ErrOpensearchRequestFailed, serr.Int("statusCode", resp.StatusCode), serr.String("body", string(b)))
 }
 
-func mapDocs[T any](hits []opensearchapi.SearchHit) ([]IDedDocument[T], error) {
+func mapDocs[T any](hits []searchHit) ([]IDedDocument[T], error) {
 	docs := make([]IDedDocument[T], 0, len(hits))
 	for _, h := range hits {
 		var doc T
-		if err := json.Unmarshal(h.Source, &doc); err != nil {
-			return nil, err
+		if len(h.Source) != 0 {
+			if err := json.Unmarshal(h.Source, &doc); err != nil {
+				return nil, err
+			}
+		}
+		var sort []any
+		if h.Sort != nil {
+			sort = make([]any, 0, len(h.Sort))
+			for _, v := range h.Sort {
+				// numbers are kept as json.Number so that long values survive a round trip through search_after
+				dec := json.NewDecoder(bytes.NewReader(v))
+				dec.UseNumber()
+				var sv any
+				if err := dec.Decode(&sv); err != nil {
+					return nil, err
+				}
+				sort = append(sort, sv)
+			}
 		}
 		docs = append(docs, IDedDocument[T]{
-			ID:       h.ID,
-			Document: &doc,
+			ID:          h.ID,
+			Document:    &doc,
+			Index:       h.Index,
+			Score:       h.Score,
+			Version:     h.Version,
+			SeqNo:       h.SeqNo,
+			PrimaryTerm: h.PrimaryTerm,
+			Sort:        sort,
+			Highlight:   h.Highlight,
+			Fields:      h.Fields,
 		})
 	}
 
@@ -444,43 +603,22 @@ func mapDocs[T any](hits []opensearchapi.SearchHit) ([]IDedDocument[T], error) {
 }
 
 func buildQuery(expr Expr, orderBy string, pag *Pagination) (*searchQuery, error) {
-	maps, err := expr.Map(OpenSearch)
+	must, filter, err := osBool(expr)
 	if err != nil {
 		return nil, err
 	}
-	if m, ok := maps.(Map); ok {
-		maps = []Map{m} // the "must" attribute shall be an array
-	}
-	arr, ok := maps.([]Map)
-	if !ok {
-		return nil, fmt.Errorf("%w %T", ErrOpensearchBadRequest, maps)
-	}
 
 	q := searchQuery{
 		Query: searchBool{
 			Bool: searchMust{
-				Must: arr,
+				Must:   must,
+				Filter: filter,
 			},
 		},
-		Sort: nil,
+		Sort: ParseSort(orderBy),
 		From: nil,
 		Size: nil,
 	}
-	if orderBy != "" {
-		dir := "asc"
-		if orderBy[0] == '-' {
-			dir = "desc"
-			orderBy = orderBy[1:]
-		}
-		field := orderBy
-		q.Sort = []map[string]any{
-			{
-				field: map[string]string{
-					"order": dir,
-				},
-			},
-		}
-	}
 	if pag != nil {
 		if pag.From+pag.Size > MaxResultWindow {
 			return nil, serr.Wrap("", ErrResultWindowExceeded,
@@ -507,9 +645,37 @@ type Convertor[T any] interface {
 }
 
 // IDedDocument is an IDed document.
+// For search hits, Index is the backing index of the hit, which differs from the searched one when searching
+// through an alias or a pattern. Score is nil if the hits are sorted by a field. Sort holds the sort values
+// which can be used to resume paging with search_after. Version and PrimaryTerm are zero unless requested
+// with [WithVersion] and [WithSeqNoPrimaryTerm]; PrimaryTerm being zero means SeqNo is unknown.
 type IDedDocument[T any] struct {
-	ID       string
-	Document *T
+	ID          string
+	Document    *T
+	Index       string
+	Score       *float64
+	Version     int
+	SeqNo       int
+	PrimaryTerm int
+	Sort        []any
+	Highlight   map[string][]string
+	Fields      map[string][]any
+}
+
+// Relations of the total number of hits to the actual number of matching documents.
+const (
+	TotalRelationEq  = "eq"
+	TotalRelationGte = "gte"
+)
+
+// SearchResponse represents search response.
+// TotalExact is false if Total is only a lower bound, which happens past the track_total_hits threshold,
+// see [WithTrackTotalHits]. Aggs is nil unless aggregations were requested.
+type SearchResponse[T any] struct {
+	Docs       []IDedDocument[T]
+	Total      int
+	TotalExact bool
+	Aggs       *AggResults
 }
 
 // ScrollResponse represents scroll response.
@@ -528,10 +694,102 @@ func Convert[T any, D any, PD interface {
 }
 
 type searchQuery struct {
-	Query searchBool       `json:"query"`
-	Sort  []map[string]any `json:"sort,omitempty"`
-	From  *int             `json:"from,omitempty"`
-	Size  *int             `json:"size,omitempty"`
+	Query            searchBool `json:"query"`
+	Sort             Sort       `json:"sort,omitempty"`
+	From             *int       `json:"from,omitempty"`
+	Size             *int       `json:"size,omitempty"`
+	Aggs             Aggs       `json:"aggs,omitempty"`
+	Highlight        *Highlight `json:"highlight,omitempty"`
+	Version          bool       `json:"version,omitempty"`
+	SeqNoPrimaryTerm bool       `json:"seq_no_primary_term,omitempty"`
+	Source           any        `json:"_source,omitempty"`
+	Fields           []string   `json:"fields,omitempty"`
+	DocValueFields   []string   `json:"docvalue_fields,omitempty"`
+	StoredFields     []string   `json:"stored_fields,omitempty"`
+	TrackTotalHits   any        `json:"track_total_hits,omitempty"`
+
+	Pit         *searchPit `json:"pit,omitempty"`
+	SearchAfter []any      `json:"search_after,omitempty"`
+
+	ignoreUnavailable bool
+}
+
+// SearchOption allows customization of the search request.
+type SearchOption func(*searchQuery)
+
+// WithVersion returns the version of each hit.
+func WithVersion() SearchOption {
+	return func(q *searchQuery) {
+		q.Version = true
+	}
+}
+
+// WithSeqNoPrimaryTerm returns the sequence number and primary term of each hit.
+func WithSeqNoPrimaryTerm() SearchOption {
+	return func(q *searchQuery) {
+		q.SeqNoPrimaryTerm = true
+	}
+}
+
+// WithIgnoreUnavailable ignores missing indices instead of failing the search, e.g. ones resolved by [TimeIndexNaming.SearchIndex].
+// It applies to [Search], [Scroll], [SearchPage], [MSearch], [Aggregate] and [Count]. [PitScroll] rejects it
+// since a point in time can't be opened over missing indices.
+func WithIgnoreUnavailable() SearchOption {
+	return func(q *searchQuery) {
+		q.ignoreUnavailable = true
+	}
+}
+
+// WithTrackTotalHits counts all the matching documents if track is true, or doesn't count them at all.
+// By default, counting stops at 10,000 documents and the total is a lower bound past that.
+func WithTrackTotalHits(track bool) SearchOption {
+	return func(q *searchQuery) {
+		q.TrackTotalHits = track
+	}
+}
+
+// WithTrackTotalHitsUpTo counts the matching documents accurately up to the threshold.
+func WithTrackTotalHitsUpTo(threshold int) SearchOption {
+	return func(q *searchQuery) {
+		q.TrackTotalHits = threshold
+	}
+}
+
+// searchParams returns the URL parameters of the search request set by the search options.
+func searchParams(q *searchQuery) opensearchapi.SearchParams {
+	var params opensearchapi.SearchParams
+	if q.ignoreUnavailable {
+		params.IgnoreUnavailable = &q.ignoreUnavailable
+	}
+	return params
+}
+
+type searchResp struct {
+	Hits         searchHits      `json:"hits"`
+	Aggregations json.RawMessage `json:"aggregations"`
+	ScrollID     *string         `json:"_scroll_id"`
+	PitID        string          `json:"pit_id"`
+}
+
+type searchHits struct {
+	Total struct {
+		Value    int    `json:"value"`
+		Relation string `json:"relation"`
+	} `json:"total"`
+	Hits []searchHit `json:"hits"`
+}
+
+type searchHit struct {
+	Index       string              `json:"_index"`
+	ID          string              `json:"_id"`
+	Score       *float64            `json:"_score"`
+	Version     int                 `json:"_version"`
+	SeqNo       int                 `json:"_seq_no"`
+	PrimaryTerm int                 `json:"_primary_term"`
+	Source      json.RawMessage     `json:"_source"`
+	Sort        []json.RawMessage   `json:"sort"`
+	Highlight   map[string][]string `json:"highlight"`
+	Fields      map[string][]any    `json:"fields"`
 }
 
 type searchBool struct {
@@ -539,7 +797,8 @@ type searchBool struct {
 }
 
 type searchMust struct {
-	Must any `json:"must"`
+	Must   []Map `json:"must,omitempty"`
+	Filter []Map `json:"filter,omitempty"`
 }
 
 // UpdateOption allows customization of Update behavior.
@@ -553,9 +812,30 @@ func WithRefresh() UpdateOption {
 	}
 }
 
+// WithIfSeqNo updates the document only if it has the given sequence number and primary term.
+// Otherwise, [ErrDocumentHasNewerVersion] is returned.
+func WithIfSeqNo(seqNo, primaryTerm int) UpdateOption {
+	return func(p *opensearchapi.UpdateParams) {
+		p.IfSeqNo = &seqNo
+		p.IfPrimaryTerm = &primaryTerm
+	}
+}
+
 // WithRetryOnConflict sets how many times the update should be retried on conflict.
 func WithRetryOnConflict(n int) UpdateOption {
 	return func(p *opensearchapi.UpdateParams) {
 		p.RetryOnConflict = &n
 	}
 }
+
+// DeleteOption allows customization of Delete behavior.
+type DeleteOption func(*opensearchapi.DocumentDeleteParams)
+
+// WithDeleteIfSeqNo deletes the document only if it has the given sequence number and primary term.
+// Otherwise, [ErrDocumentHasNewerVersion] is returned.
+func WithDeleteIfSeqNo(seqNo, primaryTerm int) DeleteOption {
+	return func(p *opensearchapi.DocumentDeleteParams) {
+		p.IfSeqNo = &seqNo
+		p.IfPrimaryTerm = &primaryTerm
+	}
+}
@@ -1,8 +1,15 @@
 package search
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/opensearch-project/opensearch-go/v4"
 	"github.com/stretchr/testify/require"
 )
 
@@ -34,3 +41,204 @@ func TestBuildQueryResultWindow(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildQueryFilterContext(t *testing.T) {
+	req := require.New(t)
+
+	expr := And{Exprs: []Expr{
+		Match{Ident: "note", Value: "fragile"},
+		Filter{Expr: And{Exprs: []Expr{
+			Eq[string]{Ident: "warehouse", Value: "W1"},
+			Terms[string]{Ident: "status", Values: []string{"shipped", "delivered"}},
+		}}},
+	}}
+
+	q, err := buildQuery(expr, "", nil)
+	req.NoError(err)
+
+	b, err := json.Marshal(q)
+	req.NoError(err)
+	req.JSONEq(`{"query":{"bool":{
+		"must":[{"match":{"note":"fragile"}}],
+		"filter":[{"term":{"warehouse":"W1"}},{"terms":{"status":["shipped","delivered"]}}]
+	}}}`, string(b))
+}
+
+func TestBuildQueryFilterOnly(t *testing.T) {
+	req := require.New(t)
+
+	q, err := buildQuery(Filter{Expr: Eq[int]{Ident: "a", Value: 1}}, "", nil)
+	req.NoError(err)
+
+	b, err := json.Marshal(q)
+	req.NoError(err)
+	req.JSONEq(`{"query":{"bool":{"filter":[{"term":{"a":1}}]}}}`, string(b))
+}
+
+func TestReadModifyWrite(t *testing.T) {
+	req := require.New(t)
+
+	seqNo := 7
+	var conditions []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprintf(w, `{"_id":"ID-1","_seq_no":%d,"_primary_term":1,"found":true,"_source":{"quantity":%d}}`, seqNo, seqNo)
+		case http.MethodPut, http.MethodPost:
+			q := r.URL.Query()
+			conditions = append(conditions, q.Get("if_seq_no")+"/"+q.Get("if_primary_term"))
+			if q.Get("if_seq_no") == "7" {
+				seqNo = 8 // somebody else was faster
+				w.WriteHeader(http.StatusConflict)
+				_, _ = io.WriteString(w, `{"error":{"type":"version_conflict_engine_exception"}}`)
+				return
+			}
+			_, _ = io.WriteString(w, `{"result":"updated"}`)
+		}
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	type doc struct {
+		Quantity int `json:"quantity"`
+	}
+	var seen []int
+	err = ReadModifyWrite(context.Background(), cl, "test-index", "ID-1", 3, func(d *doc) error {
+		seen = append(seen, d.Quantity)
+		d.Quantity++
+		return nil
+	})
+	req.NoError(err)
+	req.Equal([]int{7, 8}, seen)
+	req.Equal([]string{"7/1", "8/1"}, conditions)
+
+	seqNo = 7
+	err = ReadModifyWrite(context.Background(), cl, "test-index", "ID-1", 1, func(d *doc) error { return nil })
+	req.ErrorIs(err, ErrDocumentHasNewerVersion)
+
+	conditions = nil
+	err = ReadModifyWrite(context.Background(), cl, "test-index", "ID-1", 1, func(d *versionedDoc) error { return nil })
+	req.ErrorIs(err, ErrOpensearchBadRequest)
+	req.Empty(conditions)
+}
+
+func TestMapDocsMetadata(t *testing.T) {
+	req := require.New(t)
+
+	var resp searchResp
+	err := json.Unmarshal([]byte(`{"hits":{"hits":[
+		{"_index":"orders-v2","_id":"1","_score":null,"_version":3,"_seq_no":12,"_primary_term":2,"_source":{"name":"a"},"sort":[1790812800000123,"1"],"highlight":{"name":["<em>a</em>"]}},
+		{"_index":"orders-v1","_id":"2","_score":1.5,"_source":{"name":"b"}}
+	]}}`), &resp)
+	req.NoError(err)
+
+	type doc struct {
+		Name string `json:"name"`
+	}
+	docs, err := mapDocs[doc](resp.Hits.Hits)
+	req.NoError(err)
+	req.Len(docs, 2)
+
+	req.Equal("orders-v2", docs[0].Index)
+	req.Nil(docs[0].Score)
+	req.Equal(3, docs[0].Version)
+	req.Equal(12, docs[0].SeqNo)
+	req.Equal(2, docs[0].PrimaryTerm)
+	req.Equal([]any{json.Number("1790812800000123"), "1"}, docs[0].Sort)
+	req.Equal(map[string][]string{"name": {"<em>a</em>"}}, docs[0].Highlight)
+
+	req.Equal("orders-v1", docs[1].Index)
+	req.InDelta(1.5, *docs[1].Score, 0)
+	req.Nil(docs[1].Sort)
+}
+
+func TestBuildQueryHighlight(t *testing.T) {
+	req := require.New(t)
+
+	q, err := buildQuery(Match{Ident: "note", Value: "fragile"}, "", nil)
+	req.NoError(err)
+	fragments := 2
+	WithHighlight(Highlight{
+		Fields:            []string{"note", "address.street"},
+		FragmentSize:      80,
+		NumberOfFragments: &fragments,
+		PreTags:           []string{"<mark>"},
+		PostTags:          []string{"</mark>"},
+		Type:              HighlighterUnified,
+		Query:             Match{Ident: "note", Value: "glass"},
+	})(q)
+
+	b, err := json.Marshal(q)
+	req.NoError(err)
+	req.JSONEq(`{
+		"query":{"bool":{"must":[{"match":{"note":"fragile"}}]}},
+		"highlight":{
+			"fields":{"note":{},"address.street":{}},
+			"fragment_size":80,
+			"number_of_fragments":2,
+			"pre_tags":["<mark>"],
+			"post_tags":["</mark>"],
+			"type":"unified",
+			"highlight_query":{"match":{"note":"glass"}}
+		}
+	}`, string(b))
+}
+
+func TestTrackTotalHits(t *testing.T) {
+	req := require.New(t)
+
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"hits":{"total":{"value":10000,"relation":"gte"},"hits":[]}}`)
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	res, err := SearchWithResponse[struct{}](context.Background(), cl, "orders", Eq[string]{Ident: "state", Value: "new"}, "", nil)
+	req.NoError(err)
+	req.Equal(10000, res.Total)
+	req.False(res.TotalExact)
+	req.NotContains(bodies[0], "track_total_hits")
+
+	_, err = SearchWithResponse[struct{}](context.Background(), cl, "orders", Eq[string]{Ident: "state", Value: "new"}, "", nil, WithTrackTotalHits(false))
+	req.NoError(err)
+	req.Equal(false, bodies[1]["track_total_hits"])
+
+	_, err = SearchWithResponse[struct{}](context.Background(), cl, "orders", Eq[string]{Ident: "state", Value: "new"}, "", nil, WithTrackTotalHitsUpTo(50000))
+	req.NoError(err)
+	req.Equal(float64(50000), bodies[2]["track_total_hits"])
+}
+
+func TestCount(t *testing.T) {
+	req := require.New(t)
+
+	var path string
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"count":123456,"_shards":{"total":1,"successful":1}}`)
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	n, err := Count(context.Background(), cl, "orders", Eq[string]{Ident: "state", Value: "new"})
+	req.NoError(err)
+	req.Equal(123456, n)
+	req.Equal("/orders/_count", path)
+	req.Equal(map[string]any{"query": map[string]any{"bool": map[string]any{"must": []any{
+		map[string]any{"term": map[string]any{"state": "new"}},
+	}}}}, body)
+}
@@ -0,0 +1,185 @@
+package search
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/mailstepcz/serr"
+)
+
+// Special sort fields.
+const (
+	SortScore = "_score"
+	SortDoc   = "_doc"
+)
+
+// Placement of documents missing the sort field.
+const (
+	SortMissingFirst = "_first"
+	SortMissingLast  = "_last"
+)
+
+// Sort is a list of sort keys, the first one having the highest priority.
+type Sort []SortKey
+
+// SortKey is a sort key.
+type SortKey struct {
+	// Field is the field to sort by, [SortScore] or [SortDoc].
+	Field string
+	Desc  bool
+	// Missing is [SortMissingFirst], [SortMissingLast] or a value used for documents missing the field;
+	// it's marshalled by encoding/json.
+	Missing any
+	// Mode picks the value of array fields: min, max, sum, avg or median.
+	Mode string
+	// UnmappedType is the type assumed for indices where the field isn't mapped.
+	UnmappedType string
+	Nested       *NestedSort
+	// GeoDistance sorts by the distance of the geo point Field from the given point.
+	GeoDistance *GeoDistanceSort
+}
+
+// NestedSort specifies sorting by a field of nested documents.
+type NestedSort struct {
+	Path string
+	// Filter restricts the nested documents taken into account.
+	Filter Expr
+	// Nested is for fields nested deeper.
+	Nested *NestedSort
+}
+
+// GeoDistanceSort specifies sorting by distance from a point.
+type GeoDistanceSort struct {
+	Lat float64
+	Lon float64
+	// Unit is the distance unit, e.g. km or m.
+	Unit string
+	// DistanceType is arc or plane.
+	DistanceType string
+}
+
+// ParseSort parses comma-separated fields. A hyphen at the beginning of a field signifies descending order.
+// For instance, "-createdAt,name" sorts by createdAt descending and then by name ascending.
+func ParseSort(s string) Sort {
+	if s == "" {
+		return nil
+	}
+	fields := strings.Split(s, ",")
+	sort := make(Sort, 0, len(fields))
+	for _, f := range fields {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		key := SortKey{Field: f}
+		if f[0] == '-' {
+			key.Field = f[1:]
+			key.Desc = true
+		}
+		sort = append(sort, key)
+	}
+	return sort
+}
+
+// Map returns the query maps corresponding to the sort.
+func (s Sort) Map() ([]Map, error) {
+	l := make([]Map, 0, len(s))
+	for _, k := range s {
+		m, err := k.Map()
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, m)
+	}
+	return l, nil
+}
+
+// MarshalJSON marshals the sort into JSON.
+func (s Sort) MarshalJSON() ([]byte, error) {
+	l, err := s.Map()
+	if err != nil {
+		return nil, err
+	}
+	return appendValue(nil, l), nil
+}
+
+var _ json.Marshaler = Sort{}
+
+// Map returns the query map corresponding to the sort key.
+func (k SortKey) Map() (Map, error) {
+	dir := "asc"
+	if k.Desc {
+		dir = "desc"
+	}
+
+	if k.GeoDistance != nil {
+		g := k.GeoDistance
+		m := Map{Pairs: []KVPair{
+			{k.Field, Map{Pairs: []KVPair{
+				{"lat", g.Lat},
+				{"lon", g.Lon},
+			}}},
+			{"order", dir},
+		}}
+		if g.Unit != "" {
+			m.Pairs = append(m.Pairs, KVPair{"unit", g.Unit})
+		}
+		if g.DistanceType != "" {
+			m.Pairs = append(m.Pairs, KVPair{"distance_type", g.DistanceType})
+		}
+		if k.Mode != "" {
+			m.Pairs = append(m.Pairs, KVPair{"mode", k.Mode})
+		}
+		return Map{Pairs: []KVPair{{"_geo_distance", m}}}, nil
+	}
+
+	m := Map{Pairs: []KVPair{{"order", dir}}}
+	if k.Missing != nil {
+		missing, err := json.Marshal(k.Missing)
+		if err != nil {
+			return Map{}, serr.Wrap("marshalling missing value", err, serr.String("field", k.Field))
+		}
+		m.Pairs = append(m.Pairs, KVPair{"missing", json.RawMessage(missing)})
+	}
+	if k.Mode != "" {
+		m.Pairs = append(m.Pairs, KVPair{"mode", k.Mode})
+	}
+	if k.UnmappedType != "" {
+		m.Pairs = append(m.Pairs, KVPair{"unmapped_type", k.UnmappedType})
+	}
+	if k.Nested != nil {
+		nm, err := k.Nested.Map()
+		if err != nil {
+			return Map{}, err
+		}
+		m.Pairs = append(m.Pairs, KVPair{"nested", nm})
+	}
+	return Map{Pairs: []KVPair{{k.Field, m}}}, nil
+}
+
+// Map returns the query map corresponding to the nested sort.
+func (n NestedSort) Map() (Map, error) {
+	m := Map{Pairs: []KVPair{{"path", n.Path}}}
+	if n.Filter != nil {
+		em, err := osClause(n.Filter)
+		if err != nil {
+			return Map{}, err
+		}
+		m.Pairs = append(m.Pairs, KVPair{"filter", em})
+	}
+	if n.Nested != nil {
+		nm, err := n.Nested.Map()
+		if err != nil {
+			return Map{}, err
+		}
+		m.Pairs = append(m.Pairs, KVPair{"nested", nm})
+	}
+	return m, nil
+}
+
+// WithSort sorts the hits by the sort keys instead of the orderBy argument.
+func WithSort(s Sort) SearchOption {
+	return func(q *searchQuery) {
+		q.Sort = s
+	}
+}
@@ -0,0 +1,79 @@
+package search
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want Sort
+	}{
+		{name: "empty", in: "", want: nil},
+		{name: "single ascending", in: "name", want: Sort{{Field: "name"}}},
+		{name: "single descending", in: "-createdAt", want: Sort{{Field: "createdAt", Desc: true}}},
+		{name: "multiple", in: "-createdAt, name", want: Sort{{Field: "createdAt", Desc: true}, {Field: "name"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, ParseSort(tt.in))
+		})
+	}
+}
+
+func TestSortJSON(t *testing.T) {
+	req := require.New(t)
+
+	s := Sort{
+		{Field: SortScore, Desc: true},
+		{Field: "price", Missing: SortMissingLast, Mode: "min", UnmappedType: "double"},
+		{Field: "lines.quantity", Desc: true, Mode: "sum", Nested: &NestedSort{
+			Path:   "lines",
+			Filter: Eq[string]{Ident: "lines.status", Value: "open"},
+		}},
+		{Field: "location", GeoDistance: &GeoDistanceSort{Lat: 50.08, Lon: 14.42, Unit: "km"}},
+		{Field: SortDoc},
+	}
+
+	b, err := json.Marshal(s)
+	req.NoError(err)
+	req.JSONEq(`[
+		{"_score":{"order":"desc"}},
+		{"price":{"order":"asc","missing":"_last","mode":"min","unmapped_type":"double"}},
+		{"lines.quantity":{"order":"desc","mode":"sum","nested":{"path":"lines","filter":{"term":{"lines.status":"open"}}}}},
+		{"_geo_distance":{"location":{"lat":50.08,"lon":14.42},"order":"asc","unit":"km"}},
+		{"_doc":{"order":"asc"}}
+	]`, string(b))
+}
+
+func TestSortKeyMissing(t *testing.T) {
+	req := require.New(t)
+
+	b, err := json.Marshal(Sort{
+		{Field: "weight", Missing: int64(0)},
+		{Field: "price", Desc: true, Missing: json.Number("9.5")},
+	})
+	req.NoError(err)
+	req.JSONEq(`[{"weight":{"order":"asc","missing":0}},{"price":{"order":"desc","missing":9.5}}]`, string(b))
+
+	_, err = SortKey{Field: "weight", Missing: func() {}}.Map()
+	req.Error(err)
+}
+
+func TestBuildQuerySort(t *testing.T) {
+	req := require.New(t)
+
+	q, err := buildQuery(Eq[int]{Ident: "a", Value: 1}, "-createdAt,name", nil)
+	req.NoError(err)
+	q.addTiebreaker(PitTiebreaker)
+	q.addTiebreaker(PitTiebreaker)
+
+	b, err := json.Marshal(q.Sort)
+	req.NoError(err)
+	req.JSONEq(`[{"createdAt":{"order":"desc"}},{"name":{"order":"asc"}},{"_shard_doc":{"order":"asc"}}]`, string(b))
+}
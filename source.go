@@ -0,0 +1,135 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+)
+
+// SourceFields returns the JSON names of the fields of the struct T, including the promoted ones.
+// It's meant for source filtering so that only the fields decoded into T are fetched.
+func SourceFields[T any]() []string {
+	return sourceFields(reflect.TypeFor[T]())
+}
+
+func sourceFields(t reflect.Type) []string {
+	var fields []string
+	for _, f := range jsonFields(t) {
+		fields = append(fields, f.name)
+	}
+	return fields
+}
+
+// jsonField is a struct field along with its JSON name.
+type jsonField struct {
+	name string
+	reflect.StructField
+}
+
+// jsonFields returns the fields of the struct type as encoded into JSON, including the promoted ones.
+func jsonFields(t reflect.Type) []jsonField {
+	t = indirect(t)
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []jsonField
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && indirect(f.Type).Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(f.Type)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{name: name, StructField: f})
+	}
+	return fields
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+type sourceFilter struct {
+	Includes []string `json:"includes,omitempty"`
+	Excludes []string `json:"excludes,omitempty"`
+}
+
+// WithSource fetches only the included fields of the source, without the excluded ones.
+// Wildcards can be used in both lists.
+func WithSource(includes, excludes []string) SearchOption {
+	return func(q *searchQuery) {
+		q.Source = sourceFilter{Includes: includes, Excludes: excludes}
+	}
+}
+
+// WithSourceOf fetches only the fields of the source decoded into T, see [SourceFields].
+func WithSourceOf[T any]() SearchOption {
+	return WithSource(SourceFields[T](), nil)
+}
+
+// WithoutSource doesn't fetch the source at all. The documents of the hits are zero values,
+// this is useful along with [WithFields] or [WithDocValueFields].
+func WithoutSource() SearchOption {
+	return func(q *searchQuery) {
+		q.Source = false
+	}
+}
+
+// WithFields fetches the fields as returned by the fields API into [IDedDocument.Fields].
+func WithFields(fields ...string) SearchOption {
+	return func(q *searchQuery) {
+		q.Fields = fields
+	}
+}
+
+// WithDocValueFields fetches the doc values of the fields into [IDedDocument.Fields].
+func WithDocValueFields(fields ...string) SearchOption {
+	return func(q *searchQuery) {
+		q.DocValueFields = fields
+	}
+}
+
+// WithStoredFields fetches the stored fields into [IDedDocument.Fields].
+func WithStoredFields(fields ...string) SearchOption {
+	return func(q *searchQuery) {
+		q.StoredFields = fields
+	}
+}
+
+// GetOption allows customization of Get behavior.
+type GetOption func(*opensearchapi.DocumentGetParams)
+
+// WithGetSource fetches only the included fields of the source, without the excluded ones.
+func WithGetSource(includes, excludes []string) GetOption {
+	return func(p *opensearchapi.DocumentGetParams) {
+		p.SourceIncludes = includes
+		p.SourceExcludes = excludes
+	}
+}
+
+// WithGetSourceOf fetches only the fields of the source decoded into T, see [SourceFields].
+func WithGetSourceOf[T any]() GetOption {
+	return WithGetSource(SourceFields[T](), nil)
+}
+
+// WithGetStoredFields fetches the stored fields into [IDedDocument.Fields].
+func WithGetStoredFields(fields ...string) GetOption {
+	return func(p *opensearchapi.DocumentGetParams) {
+		p.StoredFields = fields
+	}
+}
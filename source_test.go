@@ -0,0 +1,57 @@
+package search
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type sourceBase struct {
+	ID        string `json:"id"`
+	CreatedAt string `json:"createdAt,omitempty"`
+}
+
+type sourceDoc struct {
+	sourceBase
+	Number   string `json:"number"`
+	Customer struct {
+		Name string `json:"name"`
+	} `json:"customer"`
+	Lines    []string `json:"-"`
+	Untagged int
+	internal int
+}
+
+func TestSourceFields(t *testing.T) {
+	req := require.New(t)
+
+	req.Equal([]string{"id", "createdAt", "number", "customer", "Untagged"}, SourceFields[sourceDoc]())
+	req.Nil(SourceFields[int]())
+}
+
+func TestBuildQuerySource(t *testing.T) {
+	req := require.New(t)
+
+	q, err := buildQuery(Eq[int]{Ident: "a", Value: 1}, "", nil)
+	req.NoError(err)
+	WithSourceOf[sourceBase]()(q)
+	WithDocValueFields("createdAt")(q)
+	WithStoredFields("_none_")(q)
+	WithFields("customer.*")(q)
+
+	b, err := json.Marshal(q)
+	req.NoError(err)
+	req.JSONEq(`{
+		"query":{"bool":{"must":[{"term":{"a":1}}]}},
+		"_source":{"includes":["id","createdAt"]},
+		"fields":["customer.*"],
+		"docvalue_fields":["createdAt"],
+		"stored_fields":["_none_"]
+	}`, string(b))
+
+	WithoutSource()(q)
+	b, err = json.Marshal(q)
+	req.NoError(err)
+	req.Contains(string(b), `"_source":false`)
+}
@@ -0,0 +1,196 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+
+	"github.com/mailstepcz/serr"
+)
+
+var (
+	// ErrTaskNotFound signifies that the task doesn't exist, e.g. because its result has been deleted.
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrTaskCancelFailed signifies that the task couldn't be cancelled.
+	ErrTaskCancelFailed = errors.New("task cancellation failed")
+)
+
+// DefaultTaskPollInterval is the interval of polling a task by [Task.Wait] if none is given.
+const DefaultTaskPollInterval = time.Second
+
+// TaskID identifies a task running in the cluster, in the node:id format.
+type TaskID string
+
+// Task is a handle of a long-running operation such as a reindex, update-by-query or delete-by-query.
+type Task struct {
+	ID TaskID
+	cl *opensearch.Client
+}
+
+// NewTask returns a handle of the task.
+func NewTask(cl *opensearch.Client, id TaskID) *Task {
+	return &Task{ID: id, cl: cl}
+}
+
+// TaskProgress is the progress of a task. The document counts are zero for tasks which don't report them.
+type TaskProgress struct {
+	Total            int `json:"total"`
+	Created          int `json:"created"`
+	Updated          int `json:"updated"`
+	Deleted          int `json:"deleted"`
+	Batches          int `json:"batches"`
+	VersionConflicts int `json:"version_conflicts"`
+	Noops            int `json:"noops"`
+}
+
+// TaskStatus is the status of a task.
+// Failures are the per-document failures of a completed task.
+type TaskStatus struct {
+	Completed   bool
+	Cancelled   bool
+	Action      string
+	Description string
+	RunningTime time.Duration
+	Progress    TaskProgress
+	Failures    []json.RawMessage
+	// Err is a *[TaskError] if the completed task failed as a whole or some of its documents did.
+	Err error
+}
+
+// TaskError is the failure of a task. It wraps [ErrOpensearchRequestFailed].
+type TaskError struct {
+	ID     TaskID
+	Type   string
+	Reason string
+	// Failures are the per-document failures.
+	Failures []json.RawMessage
+}
+
+func (e *TaskError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("task %s failed: %s: %s", e.ID, e.Type, e.Reason)
+	}
+	if len(e.Failures) == 0 {
+		return fmt.Sprintf("task %s failed", e.ID)
+	}
+	return fmt.Sprintf("task %s failed: %d failures, first: %s", e.ID, len(e.Failures), e.Failures[0])
+}
+
+func (e *TaskError) Unwrap() error {
+	return ErrOpensearchRequestFailed
+}
+
+// Status gets the status of the task.
+func (t *Task) Status(ctx context.Context) (*TaskStatus, error) {
+	req := opensearchapi.TasksGetReq{
+		TaskID: string(t.ID),
+	}
+	var osResp taskResp
+	resp, err := t.cl.Do(ctx, req, &osResp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, errors.Join(ErrTaskNotFound, osError(resp))
+		}
+		return nil, osError(resp)
+	}
+
+	st := TaskStatus{
+		Completed:   osResp.Completed,
+		Cancelled:   osResp.Task.Cancelled,
+		Action:      osResp.Task.Action,
+		Description: osResp.Task.Description,
+		RunningTime: time.Duration(osResp.Task.RunningTimeInNanos),
+		Progress:    osResp.Task.Status,
+		Failures:    osResp.Response.Failures,
+	}
+	switch {
+	case osResp.Error != nil:
+		st.Err = &TaskError{ID: t.ID, Type: osResp.Error.Type, Reason: osResp.Error.Reason}
+	case len(osResp.Response.Failures) > 0:
+		st.Err = &TaskError{ID: t.ID, Failures: osResp.Response.Failures}
+	}
+	return &st, nil
+}
+
+// Wait polls the status of the task every pollInterval until the task completes.
+// A non-positive interval is replaced with [DefaultTaskPollInterval].
+// It returns the final status along with its error if the task failed.
+func (t *Task) Wait(ctx context.Context, pollInterval time.Duration) (*TaskStatus, error) {
+	if pollInterval <= 0 {
+		pollInterval = DefaultTaskPollInterval
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		st, err := t.Status(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if st.Completed {
+			return st, st.Err
+		}
+
+		select {
+		case <-ctx.Done():
+			return st, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cancel cancels the task. The cancellation is asynchronous, [Task.Wait] can be used to wait for it.
+func (t *Task) Cancel(ctx context.Context) error {
+	req := opensearchapi.TasksCancelReq{
+		TaskID: string(t.ID),
+	}
+	var osResp taskCancelResp
+	resp, err := t.cl.Do(ctx, req, &osResp)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		if resp.StatusCode == http.StatusNotFound {
+			return errors.Join(ErrTaskNotFound, osError(resp))
+		}
+		return osError(resp)
+	}
+	if failures := append(osResp.NodeFailures, osResp.TaskFailures...); len(failures) > 0 {
+		return serr.Wrap("", ErrTaskCancelFailed,
+			serr.String("taskID", string(t.ID)),
+			serr.String("failure", string(failures[0])))
+	}
+	return nil
+}
+
+type taskResp struct {
+	Completed bool `json:"completed"`
+	Task      struct {
+		Action             string       `json:"action"`
+		Description        string       `json:"description"`
+		RunningTimeInNanos int64        `json:"running_time_in_nanos"`
+		Cancelled          bool         `json:"cancelled"`
+		Status             TaskProgress `json:"status"`
+	} `json:"task"`
+	Response struct {
+		Failures []json.RawMessage `json:"failures"`
+	} `json:"response"`
+	Error *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+type taskCancelResp struct {
+	NodeFailures []json.RawMessage `json:"node_failures"`
+	TaskFailures []json.RawMessage `json:"task_failures"`
+}
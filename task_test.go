@@ -0,0 +1,117 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskWait(t *testing.T) {
+	req := require.New(t)
+
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req.Equal("/_tasks/node-1:42", r.URL.Path)
+		polls++
+		w.Header().Set("Content-Type", "application/json")
+		if polls < 3 {
+			_, _ = io.WriteString(w, `{"completed":false,"task":{"action":"indices:data/write/update/byquery","running_time_in_nanos":2000000,"status":{"total":100,"updated":40,"batches":1}}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"completed":true,"task":{"action":"indices:data/write/update/byquery","status":{"total":100,"updated":99,"batches":2}},
+			"response":{"total":100,"updated":99,"failures":[{"id":"7","status":400,"cause":{"type":"mapper_parsing_exception"}}]}}`)
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	task := NewTask(cl, "node-1:42")
+	st, err := task.Status(context.Background())
+	req.NoError(err)
+	req.False(st.Completed)
+	req.Equal(40, st.Progress.Updated)
+	req.Equal(2*time.Millisecond, st.RunningTime)
+
+	st, err = task.Wait(context.Background(), time.Millisecond)
+	req.ErrorIs(err, ErrOpensearchRequestFailed)
+	var taskErr *TaskError
+	req.True(errors.As(err, &taskErr))
+	req.Len(taskErr.Failures, 1)
+	req.True(st.Completed)
+	req.Equal(99, st.Progress.Updated)
+	req.Equal(3, polls)
+}
+
+func TestTaskFailed(t *testing.T) {
+	req := require.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/_tasks/node-1:43" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"error":{"type":"resource_not_found_exception"}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"completed":true,"task":{"cancelled":true},"error":{"type":"task_cancelled_exception","reason":"by user request"}}`)
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	st, err := NewTask(cl, "node-1:42").Wait(context.Background(), time.Millisecond)
+	req.ErrorIs(err, ErrOpensearchRequestFailed)
+	req.EqualError(err, "task node-1:42 failed: task_cancelled_exception: by user request")
+	req.True(st.Cancelled)
+
+	// a zero interval is replaced with the default
+	_, err = NewTask(cl, "node-1:42").Wait(context.Background(), 0)
+	req.ErrorIs(err, ErrOpensearchRequestFailed)
+
+	_, err = NewTask(cl, "node-1:43").Status(context.Background())
+	req.ErrorIs(err, ErrTaskNotFound)
+}
+
+func TestTaskErrorMessage(t *testing.T) {
+	req := require.New(t)
+
+	req.Equal("task node-1:42 failed: task_cancelled_exception: by user request",
+		(&TaskError{ID: "node-1:42", Type: "task_cancelled_exception", Reason: "by user request"}).Error())
+	req.Equal(`task node-1:42 failed: 1 failures, first: {"id":"1"}`,
+		(&TaskError{ID: "node-1:42", Failures: []json.RawMessage{json.RawMessage(`{"id":"1"}`)}}).Error())
+	req.Equal("task node-1:42 failed", (&TaskError{ID: "node-1:42"}).Error())
+}
+
+func TestTaskCancel(t *testing.T) {
+	req := require.New(t)
+
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/_tasks/node-1:43/_cancel" {
+			_, _ = io.WriteString(w, `{"nodes":{},"task_failures":[{"task_id":43,"node_id":"node-1","status":"CONFLICT"}]}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"nodes":{"node-1":{"tasks":{}}}}`)
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+
+	req.NoError(NewTask(cl, "node-1:42").Cancel(context.Background()))
+	req.Equal("/_tasks/node-1:42/_cancel", path)
+
+	err = NewTask(cl, "node-1:43").Cancel(context.Background())
+	req.ErrorIs(err, ErrTaskCancelFailed)
+}
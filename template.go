@@ -0,0 +1,266 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+
+	"github.com/mailstepcz/serr"
+)
+
+var (
+	// ErrTemplateNotFound signifies that no template of the name exists.
+	ErrTemplateNotFound = errors.New("template not found")
+)
+
+// TemplateHashMetaKey is the _meta key of templates holding the hash of their content, see [IndexTemplateEnsure].
+const TemplateHashMetaKey = "content_hash"
+
+// IndexTemplate is a composable index template applied to new indices matching the patterns.
+type IndexTemplate struct {
+	Name          string         `json:"-"`
+	IndexPatterns []string       `json:"index_patterns"`
+	ComposedOf    []string       `json:"composed_of,omitempty"`
+	Priority      int            `json:"priority,omitempty"`
+	Version       int            `json:"version,omitempty"`
+	Template      Template       `json:"template"`
+	Meta          map[string]any `json:"_meta,omitempty"`
+}
+
+// ComponentTemplate is a reusable building block of index templates.
+type ComponentTemplate struct {
+	Name     string         `json:"-"`
+	Version  int            `json:"version,omitempty"`
+	Template Template       `json:"template"`
+	Meta     map[string]any `json:"_meta,omitempty"`
+}
+
+// Template holds the settings, mappings and aliases of a template.
+// The settings and mappings have the same format as in [IndexCreate].
+type Template struct {
+	Settings json.RawMessage          `json:"settings,omitempty"`
+	Mappings json.RawMessage          `json:"mappings,omitempty"`
+	Aliases  map[string]TemplateAlias `json:"aliases,omitempty"`
+}
+
+// TemplateAlias is an alias added to the indices created from a template.
+type TemplateAlias struct {
+	Filter       json.RawMessage `json:"filter,omitempty"`
+	Routing      string          `json:"routing,omitempty"`
+	IsWriteIndex *bool           `json:"is_write_index,omitempty"`
+}
+
+// IndexTemplatePut creates or replaces the index template.
+func IndexTemplatePut(ctx context.Context, client *opensearchapi.Client, tmpl IndexTemplate) error {
+	b, err := json.Marshal(tmpl)
+	if err != nil {
+		return serr.Wrap("marshalling index template", err)
+	}
+
+	resp, err := client.IndexTemplate.Create(ctx, opensearchapi.IndexTemplateCreateReq{
+		IndexTemplate: tmpl.Name,
+		Body:          bytes.NewReader(b),
+	})
+	if err != nil {
+		return serr.Wrap("putting index template", err, serr.String("template", tmpl.Name))
+	}
+	if !resp.Acknowledged {
+		return serr.New("index template not acknowledged", serr.String("template", tmpl.Name))
+	}
+
+	return nil
+}
+
+// IndexTemplateGet returns the index template.
+func IndexTemplateGet(ctx context.Context, client *opensearchapi.Client, name string) (*IndexTemplate, error) {
+	var osResp struct {
+		IndexTemplates []struct {
+			Name          string        `json:"name"`
+			IndexTemplate IndexTemplate `json:"index_template"`
+		} `json:"index_templates"`
+	}
+	if err := templateGet(ctx, client, opensearchapi.IndexTemplateGetReq{IndexTemplates: []string{name}}, name, &osResp); err != nil {
+		return nil, err
+	}
+	for _, t := range osResp.IndexTemplates {
+		if t.Name == name {
+			tmpl := t.IndexTemplate
+			tmpl.Name = name
+			return &tmpl, nil
+		}
+	}
+
+	return nil, serr.Wrap("", ErrTemplateNotFound, serr.String("template", name))
+}
+
+// IndexTemplateDelete deletes the index template.
+func IndexTemplateDelete(ctx context.Context, client *opensearchapi.Client, name string) error {
+	resp, err := client.IndexTemplate.Delete(ctx, opensearchapi.IndexTemplateDeleteReq{
+		IndexTemplate: name,
+	})
+	if err != nil {
+		return serr.Wrap("deleting index template", err, serr.String("template", name))
+	}
+	if !resp.Acknowledged {
+		return serr.New("index template delete not acknowledged", serr.String("template", name))
+	}
+
+	return nil
+}
+
+// IndexTemplateEnsure puts the index template unless an identical one exists, which makes it suitable for service startup.
+// The hash of the template content is stored in its _meta under [TemplateHashMetaKey] and compared with the existing one,
+// since OpenSearch normalizes the settings. It returns true if the template was put.
+func IndexTemplateEnsure(ctx context.Context, client *opensearchapi.Client, tmpl IndexTemplate) (bool, error) {
+	tmpl.Meta = withoutTemplateHash(tmpl.Meta)
+	hash, err := templateHash(tmpl)
+	if err != nil {
+		return false, err
+	}
+
+	current, err := IndexTemplateGet(ctx, client, tmpl.Name)
+	if err != nil && !errors.Is(err, ErrTemplateNotFound) {
+		return false, err
+	}
+	if current != nil && current.Meta[TemplateHashMetaKey] == hash {
+		return false, nil
+	}
+
+	tmpl.Meta = withTemplateHash(tmpl.Meta, hash)
+	if err := IndexTemplatePut(ctx, client, tmpl); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ComponentTemplatePut creates or replaces the component template.
+func ComponentTemplatePut(ctx context.Context, client *opensearchapi.Client, tmpl ComponentTemplate) error {
+	b, err := json.Marshal(tmpl)
+	if err != nil {
+		return serr.Wrap("marshalling component template", err)
+	}
+
+	resp, err := client.ComponentTemplate.Create(ctx, opensearchapi.ComponentTemplateCreateReq{
+		ComponentTemplate: tmpl.Name,
+		Body:              bytes.NewReader(b),
+	})
+	if err != nil {
+		return serr.Wrap("putting component template", err, serr.String("template", tmpl.Name))
+	}
+	if !resp.Acknowledged {
+		return serr.New("component template not acknowledged", serr.String("template", tmpl.Name))
+	}
+
+	return nil
+}
+
+// ComponentTemplateGet returns the component template.
+func ComponentTemplateGet(ctx context.Context, client *opensearchapi.Client, name string) (*ComponentTemplate, error) {
+	var osResp struct {
+		ComponentTemplates []struct {
+			Name              string            `json:"name"`
+			ComponentTemplate ComponentTemplate `json:"component_template"`
+		} `json:"component_templates"`
+	}
+	if err := templateGet(ctx, client, opensearchapi.ComponentTemplateGetReq{ComponentTemplate: name}, name, &osResp); err != nil {
+		return nil, err
+	}
+	for _, t := range osResp.ComponentTemplates {
+		if t.Name == name {
+			tmpl := t.ComponentTemplate
+			tmpl.Name = name
+			return &tmpl, nil
+		}
+	}
+
+	return nil, serr.Wrap("", ErrTemplateNotFound, serr.String("template", name))
+}
+
+// ComponentTemplateDelete deletes the component template.
+func ComponentTemplateDelete(ctx context.Context, client *opensearchapi.Client, name string) error {
+	resp, err := client.ComponentTemplate.Delete(ctx, opensearchapi.ComponentTemplateDeleteReq{
+		ComponentTemplate: name,
+	})
+	if err != nil {
+		return serr.Wrap("deleting component template", err, serr.String("template", name))
+	}
+	if !resp.Acknowledged {
+		return serr.New("component template delete not acknowledged", serr.String("template", name))
+	}
+
+	return nil
+}
+
+// ComponentTemplateEnsure puts the component template unless an identical one exists, see [IndexTemplateEnsure].
+func ComponentTemplateEnsure(ctx context.Context, client *opensearchapi.Client, tmpl ComponentTemplate) (bool, error) {
+	tmpl.Meta = withoutTemplateHash(tmpl.Meta)
+	hash, err := templateHash(tmpl)
+	if err != nil {
+		return false, err
+	}
+
+	current, err := ComponentTemplateGet(ctx, client, tmpl.Name)
+	if err != nil && !errors.Is(err, ErrTemplateNotFound) {
+		return false, err
+	}
+	if current != nil && current.Meta[TemplateHashMetaKey] == hash {
+		return false, nil
+	}
+
+	tmpl.Meta = withTemplateHash(tmpl.Meta, hash)
+	if err := ComponentTemplatePut(ctx, client, tmpl); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func templateGet(ctx context.Context, client *opensearchapi.Client, req opensearch.Request, name string, osResp any) error {
+	resp, err := client.Client.Do(ctx, req, osResp)
+	if err != nil {
+		return serr.Wrap("getting template", err, serr.String("template", name))
+	}
+	if resp.IsError() {
+		if resp.StatusCode == http.StatusNotFound {
+			return serr.Wrap("", ErrTemplateNotFound, serr.String("template", name))
+		}
+		return osError(resp)
+	}
+
+	return nil
+}
+
+// templateHash hashes the template content.
+func templateHash(tmpl any) (string, error) {
+	b, err := json.Marshal(tmpl)
+	if err != nil {
+		return "", serr.Wrap("marshalling template", err)
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:16]), nil
+}
+
+func withoutTemplateHash(meta map[string]any) map[string]any {
+	if _, ok := meta[TemplateHashMetaKey]; !ok {
+		return meta
+	}
+	meta = maps.Clone(meta)
+	delete(meta, TemplateHashMetaKey)
+	return meta
+}
+
+func withTemplateHash(meta map[string]any, hash string) map[string]any {
+	meta = maps.Clone(meta)
+	if meta == nil {
+		meta = make(map[string]any, 1)
+	}
+	meta[TemplateHashMetaKey] = hash
+	return meta
+}
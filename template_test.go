@@ -0,0 +1,127 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexTemplateEnsure(t *testing.T) {
+	req := require.New(t)
+
+	var stored map[string]any
+	puts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req.Equal("/_index_template/events", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			if stored == nil {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = io.WriteString(w, `{"error":{"type":"resource_not_found_exception"},"status":404}`)
+				return
+			}
+			// settings come back normalized
+			stored["template"].(map[string]any)["settings"] = map[string]any{"index": map[string]any{"number_of_shards": "1"}}
+			b, _ := json.Marshal(map[string]any{"index_templates": []any{map[string]any{"name": "events", "index_template": stored}}})
+			_, _ = w.Write(b)
+		case http.MethodPut:
+			puts++
+			req.NoError(json.NewDecoder(r.Body).Decode(&stored))
+			_, _ = io.WriteString(w, `{"acknowledged":true}`)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := opensearchapi.NewClient(opensearchapi.Config{Client: opensearch.Config{Addresses: []string{srv.URL}}})
+	req.NoError(err)
+	ctx := context.Background()
+
+	tmpl := IndexTemplate{
+		Name:          "events",
+		IndexPatterns: []string{"events-*"},
+		Priority:      100,
+		Template: Template{
+			Settings: json.RawMessage(`{"number_of_shards":1}`),
+			Mappings: json.RawMessage(`{"properties":{"createdAt":{"type":"date"}}}`),
+			Aliases:  map[string]TemplateAlias{"events": {}},
+		},
+		Meta: map[string]any{"owner": "wms"},
+	}
+
+	_, err = IndexTemplateGet(ctx, client, "events")
+	req.ErrorIs(err, ErrTemplateNotFound)
+
+	changed, err := IndexTemplateEnsure(ctx, client, tmpl)
+	req.NoError(err)
+	req.True(changed)
+	req.Equal([]any{"events-*"}, stored["index_patterns"])
+	req.Equal(map[string]any{"events": map[string]any{}}, stored["template"].(map[string]any)["aliases"])
+	req.Equal("wms", stored["_meta"].(map[string]any)["owner"])
+	req.Contains(stored["_meta"], TemplateHashMetaKey)
+
+	changed, err = IndexTemplateEnsure(ctx, client, tmpl)
+	req.NoError(err)
+	req.False(changed)
+
+	current, err := IndexTemplateGet(ctx, client, "events")
+	req.NoError(err)
+	req.Equal(100, current.Priority)
+	req.Equal([]string{"events-*"}, current.IndexPatterns)
+
+	tmpl.Priority = 200
+	changed, err = IndexTemplateEnsure(ctx, client, tmpl)
+	req.NoError(err)
+	req.True(changed)
+	req.Equal(2, puts)
+}
+
+func TestComponentTemplateEnsure(t *testing.T) {
+	req := require.New(t)
+
+	var stored json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req.Equal("/_component_template/events-mappings", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			if stored == nil {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = io.WriteString(w, `{"error":{"type":"resource_not_found_exception"},"status":404}`)
+				return
+			}
+			_, _ = io.WriteString(w, `{"component_templates":[{"name":"events-mappings","component_template":`+string(stored)+`}]}`)
+		case http.MethodPut:
+			stored, _ = io.ReadAll(r.Body)
+			_, _ = io.WriteString(w, `{"acknowledged":true}`)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := opensearchapi.NewClient(opensearchapi.Config{Client: opensearch.Config{Addresses: []string{srv.URL}}})
+	req.NoError(err)
+	ctx := context.Background()
+
+	tmpl := ComponentTemplate{
+		Name:     "events-mappings",
+		Template: Template{Mappings: json.RawMessage(`{"properties":{"createdAt":{"type":"date"}}}`)},
+	}
+	changed, err := ComponentTemplateEnsure(ctx, client, tmpl)
+	req.NoError(err)
+	req.True(changed)
+
+	changed, err = ComponentTemplateEnsure(ctx, client, tmpl)
+	req.NoError(err)
+	req.False(changed)
+
+	current, err := ComponentTemplateGet(ctx, client, "events-mappings")
+	req.NoError(err)
+	req.JSONEq(`{"properties":{"createdAt":{"type":"date"}}}`, string(current.Template.Mappings))
+}
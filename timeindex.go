@@ -0,0 +1,248 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+
+	"github.com/mailstepcz/serr"
+)
+
+// maxTimeIndices is the number of indices above which searches resolve to the pattern of all the indices.
+const maxTimeIndices = 100
+
+// TimeIndexNaming names time-based indices, each holding the documents of one period, e.g. a day.
+// An index is named by the prefix followed by the start of its period in UTC formatted by the layout.
+// Custom strategies are made by filling in the fields.
+type TimeIndexNaming struct {
+	Prefix string
+	// Layout formats the start of the period, see [time.Layout].
+	Layout string
+	// Start returns the start of the period containing the time, which is in UTC.
+	Start func(t time.Time) time.Time
+	// Next returns the start of the period following the one starting at the time.
+	Next func(start time.Time) time.Time
+}
+
+// DailyIndexNaming names the indices by days, e.g. events-2026.10.17.
+func DailyIndexNaming(prefix string) TimeIndexNaming {
+	return TimeIndexNaming{
+		Prefix: prefix,
+		Layout: "2006.01.02",
+		Start:  startOfDay,
+		Next: func(start time.Time) time.Time {
+			return start.AddDate(0, 0, 1)
+		},
+	}
+}
+
+// WeeklyIndexNaming names the indices by weeks starting on Monday, e.g. events-2026.10.12 for the week of 17 October 2026.
+func WeeklyIndexNaming(prefix string) TimeIndexNaming {
+	return TimeIndexNaming{
+		Prefix: prefix,
+		Layout: "2006.01.02",
+		Start: func(t time.Time) time.Time {
+			return startOfDay(t).AddDate(0, 0, -(int(t.Weekday())+6)%7)
+		},
+		Next: func(start time.Time) time.Time {
+			return start.AddDate(0, 0, 7)
+		},
+	}
+}
+
+// MonthlyIndexNaming names the indices by months, e.g. events-2026.10.
+func MonthlyIndexNaming(prefix string) TimeIndexNaming {
+	return TimeIndexNaming{
+		Prefix: prefix,
+		Layout: "2006.01",
+		Start: func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		},
+		Next: func(start time.Time) time.Time {
+			return start.AddDate(0, 1, 0)
+		},
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Index returns the index holding the documents with the timestamp.
+func (n TimeIndexNaming) Index(t time.Time) string {
+	return n.Prefix + n.Start(t.UTC()).Format(n.Layout)
+}
+
+// Pattern returns the wildcard pattern matching all the indices.
+func (n TimeIndexNaming) Pattern() string {
+	return n.Prefix + "*"
+}
+
+// Indices returns the indices holding the documents with timestamps between from and to, both inclusive.
+// A nil bound means an unbounded range, for which the pattern is returned, and so is for ranges of too many indices.
+func (n TimeIndexNaming) Indices(from, to *time.Time) []string {
+	if from == nil || to == nil {
+		return []string{n.Pattern()}
+	}
+	if to.Before(*from) {
+		// nothing matches, yet an empty list would search all the indices
+		return []string{n.Index(*from)}
+	}
+
+	var indices []string
+	end := to.UTC()
+	for start := n.Start(from.UTC()); !start.After(end); start = n.Next(start) {
+		if len(indices) == maxTimeIndices {
+			return []string{n.Pattern()}
+		}
+		indices = append(indices, start.Format(n.Layout))
+	}
+	for i, suffix := range indices {
+		indices[i] = n.Prefix + suffix
+	}
+	return indices
+}
+
+// SearchIndex returns the comma-separated indices to search with the expression, as accepted by [Search].
+// The time range is taken from the [Interval] and [Eq] nodes of the field in the expression; [And] intersects the ranges
+// of its children and [Or] unites them. Searches not limited to a range resolve to the pattern of all the indices.
+// Since some of the indices may not exist, the search should be made [WithIgnoreUnavailable],
+// which rules out point in time searches unless the range is known to be covered by existing indices.
+func (n TimeIndexNaming) SearchIndex(field string, expr Expr) string {
+	from, to := exprTimeRange(field, expr)
+	return strings.Join(n.Indices(from, to), ",")
+}
+
+// exprTimeRange returns the range of the time field the expression is limited to; nil bounds mean unbounded.
+// Exclusive bounds are treated as inclusive, which can only add an index.
+func exprTimeRange(field string, e Expr) (from, to *time.Time) {
+	switch e := e.(type) {
+	case Interval[time.Time]:
+		if e.Ident == field {
+			return e.From, e.To
+		}
+	case Eq[time.Time]:
+		if e.Ident == field {
+			return &e.Value, &e.Value
+		}
+	case Filter:
+		return exprTimeRange(field, e.Expr)
+	case And:
+		for _, c := range e.Exprs {
+			f, t := exprTimeRange(field, c)
+			if f != nil && (from == nil || f.After(*from)) {
+				from = f
+			}
+			if t != nil && (to == nil || t.Before(*to)) {
+				to = t
+			}
+		}
+		return from, to
+	case Or:
+		for i, c := range e.Exprs {
+			f, t := exprTimeRange(field, c)
+			if i == 0 {
+				from, to = f, t
+				continue
+			}
+			if f == nil || (from != nil && f.Before(*from)) {
+				from = f
+			}
+			if t == nil || (to != nil && t.After(*to)) {
+				to = t
+			}
+		}
+		return from, to
+	}
+	return nil, nil
+}
+
+// TimeIndexRouter resolves the indices to write documents into by their timestamps and creates the missing ones.
+// It's safe for concurrent use.
+type TimeIndexRouter struct {
+	client   *opensearchapi.Client
+	naming   TimeIndexNaming
+	template *IndexTemplate
+
+	mu              sync.Mutex
+	templateEnsured bool
+	known           map[string]struct{}
+}
+
+// NewTimeIndexRouter creates a router writing into indices named by the naming strategy.
+// Unless the template is nil, it's ensured by [IndexTemplateEnsure] before the first index is created,
+// so its index patterns should match the pattern of the naming strategy.
+func NewTimeIndexRouter(client *opensearchapi.Client, naming TimeIndexNaming, template *IndexTemplate) *TimeIndexRouter {
+	return &TimeIndexRouter{
+		client:   client,
+		naming:   naming,
+		template: template,
+		known:    make(map[string]struct{}),
+	}
+}
+
+// Naming returns the naming strategy of the router.
+func (r *TimeIndexRouter) Naming() TimeIndexNaming {
+	return r.naming
+}
+
+// WriteIndex returns the index to write a document with the timestamp into, creating the index on first write.
+// The settings and mappings of the created index come from the index templates.
+func (r *TimeIndexRouter) WriteIndex(ctx context.Context, t time.Time) (string, error) {
+	index := r.naming.Index(t)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.known[index]; ok {
+		return index, nil
+	}
+
+	if r.template != nil && !r.templateEnsured {
+		if _, err := IndexTemplateEnsure(ctx, r.client, *r.template); err != nil {
+			return "", err
+		}
+		r.templateEnsured = true
+	}
+
+	exists, err := IndexExists(ctx, r.client, index)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		if err := IndexCreate(ctx, r.client, index, nil, json.RawMessage(`{}`)); err != nil && !indexAlreadyExists(err) {
+			return "", err
+		}
+	}
+
+	r.known[index] = struct{}{}
+	return index, nil
+}
+
+// indexAlreadyExists returns true if the index creation failed since another writer has created the index.
+func indexAlreadyExists(err error) bool {
+	var osErr *opensearch.StructError
+	return errors.As(err, &osErr) && osErr.Err.Type == "resource_already_exists_exception"
+}
+
+// RouteBulk sets the indices of the bulk operations by the timestamps of their documents, see [TimeIndexRouter.WriteIndex].
+// Operations without documents, e.g. deletes, are left intact and must have their indices set.
+func RouteBulk[T any](ctx context.Context, r *TimeIndexRouter, ops []BulkOperation[T], timestamp func(*T) time.Time) error {
+	for i := range ops {
+		if ops[i].Doc == nil {
+			continue
+		}
+		index, err := r.WriteIndex(ctx, timestamp(ops[i].Doc))
+		if err != nil {
+			return serr.Wrap("routing bulk operation", err, serr.String("id", ops[i].ID))
+		}
+		ops[i].Index = index
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package search
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeIndexNaming(t *testing.T) {
+	req := require.New(t)
+
+	ts := time.Date(2026, 10, 17, 23, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	req.Equal("events-2026.10.17", DailyIndexNaming("events-").Index(ts))
+	req.Equal("events-2026.10.12", WeeklyIndexNaming("events-").Index(ts))
+	req.Equal("events-2026.10.19", WeeklyIndexNaming("events-").Index(ts.AddDate(0, 0, 2)))
+	req.Equal("events-2026.10", MonthlyIndexNaming("events-").Index(ts))
+	req.Equal("events-*", MonthlyIndexNaming("events-").Pattern())
+
+	yearly := TimeIndexNaming{
+		Prefix: "archive-",
+		Layout: "2006",
+		Start: func(t time.Time) time.Time {
+			return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		},
+		Next: func(start time.Time) time.Time {
+			return start.AddDate(1, 0, 0)
+		},
+	}
+	req.Equal("archive-2026", yearly.Index(ts))
+
+	from := time.Date(2026, 9, 20, 12, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	req.Equal([]string{"events-2026.09", "events-2026.10", "events-2026.11"}, MonthlyIndexNaming("events-").Indices(&from, &to))
+	req.Equal([]string{"events-2026.09.14", "events-2026.09.21", "events-2026.09.28", "events-2026.10.05", "events-2026.10.12", "events-2026.10.19", "events-2026.10.26"},
+		WeeklyIndexNaming("events-").Indices(&from, &to))
+	req.Equal([]string{"events-*"}, DailyIndexNaming("events-").Indices(&from, nil))
+	req.Equal([]string{"events-2026.11.01"}, DailyIndexNaming("events-").Indices(&to, &from))
+
+	longAgo := from.AddDate(-1, 0, 0)
+	req.Equal([]string{"events-*"}, DailyIndexNaming("events-").Indices(&longAgo, &to))
+}
+
+func TestTimeIndexNamingSearchIndex(t *testing.T) {
+	req := require.New(t)
+
+	naming := MonthlyIndexNaming("events-")
+	day := func(month time.Month, d int) *time.Time {
+		t := time.Date(2026, month, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	req.Equal("events-*", naming.SearchIndex("createdAt", Eq[string]{Ident: "state", Value: "new"}))
+	req.Equal("events-2026.10", naming.SearchIndex("createdAt", Eq[time.Time]{Ident: "createdAt", Value: *day(10, 17)}))
+	req.Equal("events-*", naming.SearchIndex("createdAt", Interval[time.Time]{Ident: "updatedAt", From: day(9, 1), To: day(10, 1)}))
+
+	req.Equal("events-2026.09,events-2026.10", naming.SearchIndex("createdAt", And{Exprs: []Expr{
+		Eq[string]{Ident: "state", Value: "new"},
+		Filter{Expr: Interval[time.Time]{Ident: "createdAt", From: day(1, 1)}},
+		Interval[time.Time]{Ident: "createdAt", From: day(9, 15), To: day(12, 1)},
+		Interval[time.Time]{Ident: "createdAt", To: day(10, 20)},
+	}}))
+
+	req.Equal("events-2026.03,events-2026.04,events-2026.05,events-2026.06", naming.SearchIndex("createdAt", Or{Exprs: []Expr{
+		Interval[time.Time]{Ident: "createdAt", From: day(5, 2), To: day(6, 1)},
+		Interval[time.Time]{Ident: "createdAt", From: day(3, 5), To: day(4, 1)},
+	}}))
+	req.Equal("events-*", naming.SearchIndex("createdAt", Or{Exprs: []Expr{
+		Interval[time.Time]{Ident: "createdAt", From: day(5, 2), To: day(6, 1)},
+		Eq[string]{Ident: "state", Value: "new"},
+	}}))
+	req.Equal("events-*", naming.SearchIndex("createdAt", Not{Expr: Interval[time.Time]{Ident: "createdAt", From: day(5, 2), To: day(6, 1)}}))
+}
+
+func TestTimeIndexRouter(t *testing.T) {
+	req := require.New(t)
+
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := r.Method + " " + r.URL.Path
+		calls = append(calls, call)
+		w.Header().Set("Content-Type", "application/json")
+		switch call {
+		case "GET /_index_template/events":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"error":{"type":"resource_not_found_exception"},"status":404}`)
+		case "PUT /_index_template/events", "PUT /events-2026.10":
+			_, _ = io.WriteString(w, `{"acknowledged":true}`)
+		case "HEAD /events-2026.10", "HEAD /events-2026.09":
+			w.WriteHeader(http.StatusNotFound)
+		case "PUT /events-2026.09":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":{"type":"resource_already_exists_exception","reason":"index already exists"},"status":400}`)
+		case "HEAD /events-2026.08":
+		case "POST /events-2026.08,events-2026.09/_search":
+			req.Equal("true", r.URL.Query().Get("ignore_unavailable"))
+			_, _ = io.WriteString(w, `{"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":"unexpected call"}`)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := opensearchapi.NewClient(opensearchapi.Config{Client: opensearch.Config{Addresses: []string{srv.URL}}})
+	req.NoError(err)
+	ctx := context.Background()
+
+	router := NewTimeIndexRouter(client, MonthlyIndexNaming("events-"), &IndexTemplate{
+		Name:          "events",
+		IndexPatterns: []string{"events-*"},
+	})
+
+	type event struct {
+		Name      string    `json:"name"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+	ops := []BulkOperation[event]{
+		{OperationType: OpIndex, ID: "1", Doc: &event{CreatedAt: time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)}},
+		{OperationType: OpIndex, ID: "2", Doc: &event{CreatedAt: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}},
+		{OperationType: OpIndex, ID: "3", Doc: &event{CreatedAt: time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC)}},
+		{OperationType: OpIndex, ID: "4", Doc: &event{CreatedAt: time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC)}},
+		{OperationType: OpDelete, ID: "5", Index: "events-2026.07"},
+	}
+	req.NoError(RouteBulk(ctx, router, ops, func(e *event) time.Time { return e.CreatedAt }))
+	req.Equal("events-2026.10", ops[0].Index)
+	req.Equal("events-2026.10", ops[1].Index)
+	req.Equal("events-2026.09", ops[2].Index)
+	req.Equal("events-2026.08", ops[3].Index)
+	req.Equal("events-2026.07", ops[4].Index)
+	req.Equal([]string{
+		"GET /_index_template/events",
+		"PUT /_index_template/events",
+		"HEAD /events-2026.10",
+		"PUT /events-2026.10",
+		"HEAD /events-2026.09",
+		"PUT /events-2026.09",
+		"HEAD /events-2026.08",
+	}, calls)
+
+	index := router.Naming().SearchIndex("createdAt", Interval[time.Time]{Ident: "createdAt", From: &ops[3].Doc.CreatedAt, To: &ops[2].Doc.CreatedAt})
+	_, _, err = Search[event](ctx, client.Client, index, And{}, "", nil, WithIgnoreUnavailable())
+	req.NoError(err)
+}
+
+func TestWithIgnoreUnavailable(t *testing.T) {
+	req := require.New(t)
+
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		req.Equal("true", r.URL.Query().Get("ignore_unavailable"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/events-2026.10.17,events-2026.10.18/_count":
+			_, _ = io.WriteString(w, `{"count":0}`)
+		default:
+			_, _ = io.WriteString(w, `{"_scroll_id":"s1","hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`)
+		}
+	}))
+	defer srv.Close()
+
+	cl, err := opensearch.NewClient(opensearch.Config{Addresses: []string{srv.URL}})
+	req.NoError(err)
+	ctx := context.Background()
+	index := "events-2026.10.17,events-2026.10.18"
+
+	n, err := Count(ctx, cl, index, And{}, WithIgnoreUnavailable())
+	req.NoError(err)
+	req.Zero(n)
+	_, err = StartScroll[struct{}](ctx, cl, index, And{}, "", 10, time.Minute, WithIgnoreUnavailable())
+	req.NoError(err)
+	_, err = SearchPage[struct{}](ctx, cl, index, And{}, "", CursorPagination{Size: 10}, WithIgnoreUnavailable())
+	req.NoError(err)
+	req.Len(calls, 3)
+
+	_, err = PitScroll[struct{}](ctx, cl, index, And{}, "", 10, time.Minute, WithIgnoreUnavailable())
+	req.ErrorIs(err, ErrOpensearchBadRequest)
+	req.Len(calls, 3)
+}